@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A11yEntry is the ARIA metadata of a single element.
+type A11yEntry struct {
+	Role       string `json:"role,omitempty"`
+	Label      string `json:"label,omitempty"`
+	LabelledBy string `json:"labelledby,omitempty"`
+}
+
+// extractAccessibility collects the role, aria-label and aria-labelledby
+// attributes of every element that sets one of them, grouped by tag name.
+func extractAccessibility(doc *goquery.Document) map[string][]A11yEntry {
+	result := make(map[string][]A11yEntry)
+	doc.Find("[role], [aria-label], [aria-labelledby]").Each(func(i int, s *goquery.Selection) {
+		role, _ := s.Attr("role")
+		label, _ := s.Attr("aria-label")
+		labelledBy, _ := s.Attr("aria-labelledby")
+		tag := goquery.NodeName(s)
+		result[tag] = append(result[tag], A11yEntry{
+			Role:       strings.TrimSpace(role),
+			Label:      strings.TrimSpace(label),
+			LabelledBy: strings.TrimSpace(labelledBy),
+		})
+	})
+	return result
+}
+
+// String formats the entry for plain-text output.
+func (e A11yEntry) String() string {
+	var parts []string
+	if e.Role != "" {
+		parts = append(parts, "role="+e.Role)
+	}
+	if e.Label != "" {
+		parts = append(parts, "aria-label="+e.Label)
+	}
+	if e.LabelledBy != "" {
+		parts = append(parts, "aria-labelledby="+e.LabelledBy)
+	}
+	return strings.Join(parts, " ")
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
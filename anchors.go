@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// checkAnchors returns the same-page fragment links, such as "#section",
+// whose target has no element with a matching id or name. The empty
+// fragment and "#top" always scroll to the top of the page and are never
+// reported.
+func checkAnchors(doc *goquery.Document, pageURL *url.URL) []string {
+	targets := make(map[string]bool)
+	doc.Find("[id], a[name]").Each(func(i int, s *goquery.Selection) {
+		if id, ok := s.Attr("id"); ok {
+			targets[id] = true
+		}
+		if name, ok := s.Attr("name"); ok {
+			targets[name] = true
+		}
+	})
+
+	var broken []string
+	reported := make(map[string]bool)
+	doc.Find("a[href], area[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || u.Fragment == "" || strings.EqualFold(u.Fragment, "top") {
+			return
+		}
+		if pageURL != nil {
+			u = pageURL.ResolveReference(u)
+			page := *pageURL
+			page.Fragment = ""
+			if normalizeURL(u) != page.String() {
+				return
+			}
+		} else if !strings.HasPrefix(strings.TrimSpace(href), "#") {
+			return
+		}
+		if !targets[u.Fragment] && !reported[u.Fragment] {
+			reported[u.Fragment] = true
+			broken = append(broken, "#"+u.Fragment)
+		}
+	})
+	return broken
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Attribution is a person or organization credited for a page.
+type Attribution struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Source string `json:"source"` // json-ld, meta or rel
+}
+
+// String formats the attribution for plain-text output.
+func (a Attribution) String() string {
+	switch {
+	case a.Name != "" && a.URL != "":
+		return a.Name + " <" + a.URL + ">"
+	case a.Name != "":
+		return a.Name
+	}
+	return a.URL
+}
+
+// extractAuthors returns the page's authors, preferring structured data:
+// the author of the first JSON-LD object that has one, then article:author
+// and author meta tags, then rel="author" links.
+func extractAuthors(doc *goquery.Document, base *url.URL) []Attribution {
+	for _, obj := range jsonLDObjects(doc) {
+		if authors := jsonLDAttributions(obj["author"], base); len(authors) > 0 {
+			return authors
+		}
+	}
+
+	var authors []Attribution
+	for _, key := range []string{"article:author", "author"} {
+		doc.Find(`meta[property="` + key + `"], meta[name="` + key + `"]`).Each(func(i int, s *goquery.Selection) {
+			content, _ := s.Attr("content")
+			if a, ok := attributionFromString(content, "meta", base); ok {
+				authors = append(authors, a)
+			}
+		})
+		if len(authors) > 0 {
+			return authors
+		}
+	}
+
+	doc.Find(`a[rel~="author"], link[rel~="author"]`).Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		a := Attribution{Name: strings.Join(strings.Fields(s.Text()), " "), Source: "rel"}
+		a.URL, _ = resolveURL(base, href)
+		if a.Name != "" || a.URL != "" {
+			authors = append(authors, a)
+		}
+	})
+	return authors
+}
+
+// extractPublisher returns the organization that published the page, from
+// JSON-LD or else the article:publisher and og:site_name meta tags.
+func extractPublisher(doc *goquery.Document, base *url.URL) *Attribution {
+	for _, obj := range jsonLDObjects(doc) {
+		if publishers := jsonLDAttributions(obj["publisher"], base); len(publishers) > 0 {
+			return &publishers[0]
+		}
+	}
+	p, _ := attributionFromString(metaContent(doc, "article:publisher"), "meta", base)
+	if name := metaContent(doc, "og:site_name"); name != "" {
+		p.Name, p.Source = name, "meta"
+	}
+	if p.Source == "" {
+		return nil
+	}
+	return &p
+}
+
+// jsonLDAttributions converts a JSON-LD author or publisher value, which may
+// be a name, an object with a name and url, or a list of either.
+func jsonLDAttributions(v any, base *url.URL) []Attribution {
+	switch t := v.(type) {
+	case string:
+		if a, ok := attributionFromString(t, "json-ld", base); ok {
+			return []Attribution{a}
+		}
+	case map[string]any:
+		a := Attribution{Name: jsonLDString(t, "name"), Source: "json-ld"}
+		a.URL, _ = resolveURL(base, firstNonEmpty(jsonLDString(t, "url"), jsonLDString(t, "@id")))
+		if a.Name != "" || a.URL != "" {
+			return []Attribution{a}
+		}
+	case []any:
+		var all []Attribution
+		for _, item := range t {
+			all = append(all, jsonLDAttributions(item, base)...)
+		}
+		return all
+	}
+	return nil
+}
+
+// attributionFromString treats s as a profile URL if it looks like one and
+// as a name otherwise.
+func attributionFromString(s, source string, base *url.URL) (Attribution, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Attribution{}, false
+	}
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "/") {
+		u, _ := resolveURL(base, s)
+		return Attribution{URL: u, Source: source}, true
+	}
+	return Attribution{Name: s, Source: source}, true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// benchRequest fetches and parses url once, returning the body size.
+func benchRequest(ctx context.Context, url string, opts Options) (int64, error) {
+	resp, err := fetchPage(ctx, url, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body := &countingReader{r: resp.Body}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, body)
+		return body.n, &statusError{Code: resp.StatusCode}
+	}
+	_, err = parsePage(body, resp.Request.URL, opts)
+	return body.n, err
+}
+
+// percentile returns the p-th percentile (0-100) of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// runBenchmark scrapes url opts.Repeat times in a row and prints throughput,
+// latency percentiles, the error rate and the total bytes received.
+func runBenchmark(ctx context.Context, url string, opts Options) {
+	var latencies []time.Duration
+	var totalBytes int64
+	errCount := 0
+
+	start := time.Now()
+	for i := 0; i < opts.Repeat; i++ {
+		t := time.Now()
+		n, err := benchRequest(ctx, url, opts)
+		latencies = append(latencies, time.Since(t))
+		totalBytes += n
+		if err != nil {
+			errCount++
+		}
+	}
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("Requests:     %d\n", opts.Repeat)
+	fmt.Printf("Duration:     %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("Requests/sec: %.2f\n", float64(opts.Repeat)/elapsed.Seconds())
+	fmt.Printf("Latency p50:  %s\n", percentile(latencies, 50))
+	fmt.Printf("Latency p95:  %s\n", percentile(latencies, 95))
+	fmt.Printf("Latency p99:  %s\n", percentile(latencies, 99))
+	fmt.Printf("Errors:       %d (%.1f%%)\n", errCount, 100*float64(errCount)/float64(opts.Repeat))
+	fmt.Printf("Total bytes:  %d\n", totalBytes)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Crumb is one step of a breadcrumb trail.
+type Crumb struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"` // Empty for the current page in some markup
+}
+
+// breadcrumbSelector matches breadcrumb navigation that carries no
+// structured data.
+const breadcrumbSelector = `nav[aria-label*="breadcrumb"], nav[aria-label*="Breadcrumb"], .breadcrumb, .breadcrumbs, [class*="breadcrumb"]`
+
+// extractBreadcrumbs returns the page's breadcrumb trail from its
+// BreadcrumbList JSON-LD or microdata, or else from breadcrumb navigation
+// markup. When the page has several trails the first is used.
+func extractBreadcrumbs(doc *goquery.Document, base *url.URL) []Crumb {
+	if list := findJSONLD(doc, "BreadcrumbList"); list != nil {
+		if crumbs := jsonLDBreadcrumbs(list, base); len(crumbs) > 0 {
+			return crumbs
+		}
+	}
+
+	if scope := microdataScope(doc, "BreadcrumbList"); scope.Length() > 0 {
+		var crumbs []positionedCrumb
+		scope.Find(`[itemprop~="itemListElement"]`).Each(func(i int, s *goquery.Selection) {
+			c := Crumb{Name: strings.Join(strings.Fields(itemprop(s, "name")), " ")}
+			if item := s.Find(`[itemprop~="item"]`).First(); item.Length() > 0 {
+				c.URL, _ = resolveURL(base, itempropValue(item))
+				if c.Name == "" {
+					c.Name = strings.Join(strings.Fields(item.Text()), " ")
+				}
+			} else {
+				c.URL = resolvedAttr(s.Find("a[href]").First(), "href", base)
+			}
+			crumbs = append(crumbs, positionedCrumb{c, parsePosition(itemprop(s, "position"), i)})
+		})
+		if trail := sortCrumbs(crumbs); len(trail) > 0 {
+			return trail
+		}
+	}
+
+	nav := doc.Find(breadcrumbSelector).First()
+	items := nav.Find("li")
+	if items.Length() == 0 {
+		items = nav.Find("a[href]")
+	}
+	var crumbs []Crumb
+	items.Each(func(i int, s *goquery.Selection) {
+		link := s.Filter("a[href]").AddSelection(s.Find("a[href]")).First()
+		c := Crumb{
+			Name: strings.Join(strings.Fields(s.Text()), " "),
+			URL:  resolvedAttr(link, "href", base),
+		}
+		if c.Name != "" {
+			crumbs = append(crumbs, c)
+		}
+	})
+	return crumbs
+}
+
+// positionedCrumb is a crumb with its declared position in the list.
+type positionedCrumb struct {
+	crumb    Crumb
+	position float64
+}
+
+// jsonLDBreadcrumbs converts the itemListElement of a BreadcrumbList. The
+// item of a ListItem is either a URL or an object with an @id and name.
+func jsonLDBreadcrumbs(list map[string]any, base *url.URL) []Crumb {
+	var crumbs []positionedCrumb
+	for i, el := range jsonLDObjectList(list, "itemListElement") {
+		c := Crumb{Name: jsonLDString(el, "name")}
+		ref := jsonLDString(el, "item")
+		if item := jsonLDObject(el, "item"); item != nil {
+			ref = firstNonEmpty(jsonLDString(item, "@id"), jsonLDString(item, "url"))
+			c.Name = firstNonEmpty(c.Name, jsonLDString(item, "name"))
+		}
+		c.URL, _ = resolveURL(base, ref)
+		crumbs = append(crumbs, positionedCrumb{c, parsePosition(jsonLDString(el, "position"), i)})
+	}
+	return sortCrumbs(crumbs)
+}
+
+// parsePosition parses a ListItem position, falling back to the item's
+// index in the document.
+func parsePosition(s string, index int) float64 {
+	if p, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+		return p
+	}
+	return float64(index + 1)
+}
+
+// sortCrumbs orders the crumbs by position and drops those without a name.
+func sortCrumbs(crumbs []positionedCrumb) []Crumb {
+	sort.SliceStable(crumbs, func(i, j int) bool { return crumbs[i].position < crumbs[j].position })
+	var trail []Crumb
+	for _, c := range crumbs {
+		if c.crumb.Name != "" {
+			trail = append(trail, c.crumb)
+		}
+	}
+	return trail
+}
+
+// formatTrail joins the crumb names for plain-text output.
+func formatTrail(crumbs []Crumb) string {
+	names := make([]string, len(crumbs))
+	for i, c := range crumbs {
+		names[i] = c.Name
+	}
+	return strings.Join(names, " > ")
+}
+
+// SiteNode is a section of the site structure inferred from breadcrumbs:
+// a crumb, the number of crawled pages whose trail passes through it, and
+// the sections below it.
+type SiteNode struct {
+	Name     string      `json:"name"`
+	URL      string      `json:"url,omitempty"`
+	Pages    int         `json:"pages"`
+	Children []*SiteNode `json:"children,omitempty"`
+}
+
+// siteTrail returns the page's place in the site tree: its breadcrumb
+// trail, ending with the page itself if the trail does not already.
+func siteTrail(crumbs []Crumb, page, title string) []Crumb {
+	if len(crumbs) == 0 || page == "" {
+		return crumbs
+	}
+	last := crumbs[len(crumbs)-1]
+	if last.URL == "" {
+		last.URL = page
+		return append(crumbs[:len(crumbs)-1:len(crumbs)-1], last)
+	}
+	if !sameCrumbURL(last.URL, page) {
+		return append(crumbs[:len(crumbs):len(crumbs)], Crumb{Name: firstNonEmpty(title, page), URL: page})
+	}
+	return crumbs
+}
+
+// sameCrumbURL reports whether two crumb URLs name the same page, ignoring
+// fragments and a trailing slash.
+func sameCrumbURL(a, b string) bool {
+	return crumbKey(Crumb{URL: a}) == crumbKey(Crumb{URL: b})
+}
+
+// crumbKey identifies the section a crumb names: its URL, or its name when
+// it has no link.
+func crumbKey(c Crumb) string {
+	if c.URL == "" {
+		return "name:" + strings.ToLower(c.Name)
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return c.URL
+	}
+	return strings.TrimSuffix(normalizeURL(u), "/")
+}
+
+// addTrail adds one page's trail to the tree rooted at roots.
+func addTrail(roots []*SiteNode, trail []Crumb) []*SiteNode {
+	if len(trail) == 0 {
+		return roots
+	}
+	node := findSiteNode(roots, trail[0])
+	if node == nil {
+		node = &SiteNode{Name: trail[0].Name, URL: trail[0].URL}
+		roots = append(roots, node)
+	}
+	node.Pages++
+	node.Children = addTrail(node.Children, trail[1:])
+	return roots
+}
+
+// mergeSiteTree adds the nodes of b into the tree a.
+func mergeSiteTree(a, b []*SiteNode) []*SiteNode {
+	for _, n := range b {
+		node := findSiteNode(a, Crumb{Name: n.Name, URL: n.URL})
+		if node == nil {
+			node = &SiteNode{Name: n.Name, URL: n.URL}
+			a = append(a, node)
+		}
+		node.Pages += n.Pages
+		node.Children = mergeSiteTree(node.Children, n.Children)
+	}
+	return a
+}
+
+// findSiteNode returns the node among nodes for the crumb's section.
+func findSiteNode(nodes []*SiteNode, c Crumb) *SiteNode {
+	key := crumbKey(c)
+	for _, n := range nodes {
+		if crumbKey(Crumb{Name: n.Name, URL: n.URL}) == key {
+			return n
+		}
+	}
+	return nil
+}
+
+// writeSiteTree writes the tree as an indented outline.
+func writeSiteTree(w io.Writer, nodes []*SiteNode, indent string) {
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s- %s", indent, n.Name)
+		if n.URL != "" {
+			fmt.Fprintf(w, " (%s)", n.URL)
+		}
+		if n.Pages == 1 {
+			fmt.Fprintln(w, " [1 page]")
+		} else {
+			fmt.Fprintf(w, " [%d pages]\n", n.Pages)
+		}
+		writeSiteTree(w, n.Children, indent+"  ")
+	}
+}
+
+// writeSiteTreeDot writes the site tree as a GraphViz digraph with an edge
+// from each section to each section below it.
+func writeSiteTreeDot(w io.Writer, roots []*SiteNode) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph site {")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	var walk func(parent string, nodes []*SiteNode)
+	walk = func(parent string, nodes []*SiteNode) {
+		for _, n := range nodes {
+			id := dotEscape.Replace(crumbKey(Crumb{Name: n.Name, URL: n.URL}))
+			fmt.Fprintf(bw, "  \"%s\" [label=\"%s\"];\n", id, dotEscape.Replace(n.Name))
+			if parent != "" {
+				fmt.Fprintf(bw, "  \"%s\" -> \"%s\";\n", parent, id)
+			}
+			walk(id, n.Children)
+		}
+	}
+	walk("", roots)
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cachePath returns the file req's response is cached in under dir.
+func cachePath(dir string, req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(dir, hex.EncodeToString(sum[:]))
+}
+
+// loadCached returns the cached response to req if there is one younger than
+// ttl; a ttl of 0 never expires. The response's Request carries the URL the
+// original request ended at after redirects.
+func loadCached(dir string, req *http.Request, ttl time.Duration) (*http.Response, bool) {
+	path := cachePath(dir, req)
+	info, err := os.Stat(path)
+	if err != nil || (ttl > 0 && time.Since(info.ModTime()) > ttl) {
+		return nil, false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	br := bufio.NewReader(file)
+	line, err := br.ReadString('\n')
+	final, perr := url.Parse(strings.TrimSpace(line))
+	if err != nil || perr != nil {
+		file.Close()
+		return nil, false
+	}
+	finalReq := req.Clone(req.Context())
+	finalReq.URL = final
+	resp, err := http.ReadResponse(br, finalReq)
+	if err != nil {
+		file.Close()
+		return nil, false
+	}
+	resp.Body = &fileBody{ReadCloser: resp.Body, file: file}
+	return resp, true
+}
+
+// fileBody closes the cache file along with the response body.
+type fileBody struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (b *fileBody) Close() error {
+	b.ReadCloser.Close()
+	return b.file.Close()
+}
+
+// storeCached writes resp to the cache for the request that produced it,
+// unless its body was cut short or matches skip, the -retry-if-body-matches
+// soft failures. The body is read into memory and resp.Body replaced so it
+// can still be read by the caller, ending with the read error if there was
+// one.
+func storeCached(dir string, req *http.Request, resp *http.Response, skip *regexp.Regexp) error {
+	body, err := bufferBody(resp)
+	if err != nil || (skip != nil && skip.Match(body)) {
+		return nil
+	}
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return fmt.Errorf("error caching response: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error caching response: %v", err)
+	}
+	path := cachePath(dir, req)
+	tmp := path + ".tmp"
+	content := append([]byte(resp.Request.URL.String()+"\n"), dump...)
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("error caching response: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error caching response: %v", err)
+	}
+	return nil
+}
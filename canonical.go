@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractCanonical returns the absolute URL of the page's
+// <link rel="canonical">, if any.
+func extractCanonical(doc *goquery.Document, base *url.URL) string {
+	return resolvedAttr(doc.Find(`link[rel~="canonical"][href]`).First(), "href", base)
+}
+
+// canonicalTarget returns the canonical URL of a scraped page when it names
+// a different page on the same host, the only case -follow-canonical
+// follows. Fragments are ignored.
+func canonicalTarget(data ScrapeData) (string, bool) {
+	if data.canonical == "" || data.URL == "" {
+		return "", false
+	}
+	canonical, err := url.Parse(data.canonical)
+	if err != nil {
+		return "", false
+	}
+	page, err := url.Parse(data.URL)
+	if err != nil || !strings.EqualFold(canonical.Host, page.Host) {
+		return "", false
+	}
+	target := normalizeURL(canonical)
+	if target == normalizeURL(page) {
+		return "", false
+	}
+	return target, true
+}
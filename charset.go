@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Byte-order marks recognised at the start of a response body.
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeBOM checks r for a byte-order mark and returns a reader that yields
+// UTF-8 without the mark, along with the name of the encoding the mark
+// declared. If there is no mark r's content is returned unchanged and the
+// name is empty.
+func decodeBOM(r io.Reader) (io.Reader, string) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		br.Discard(len(bomUTF8))
+		return br, "utf-8"
+	case bytes.HasPrefix(head, bomUTF16LE):
+		dec := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+		return transform.NewReader(br, dec), "utf-16le"
+	case bytes.HasPrefix(head, bomUTF16BE):
+		dec := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		return transform.NewReader(br, dec), "utf-16be"
+	}
+	return br, ""
+}
+
+// Encoding is the character encoding a page was decoded with and where it
+// came from: "bom", "header", "meta" or "fallback" when it was guessed.
+type Encoding struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// String formats the encoding for plain-text output.
+func (e Encoding) String() string {
+	return e.Name + " (" + e.Source + ")"
+}
+
+// metaCharset matches the charset declared by <meta charset> or
+// <meta http-equiv="Content-Type" content="...; charset=...">.
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.-]+)`)
+
+// charsetSniffBytes is how much of the body is searched for a <meta>
+// charset, as in the HTML encoding sniffing algorithm.
+const charsetSniffBytes = 1024
+
+// decodeCharset returns a reader that yields r's content as UTF-8 and the
+// encoding it was decoded from. The encoding is taken from a byte-order mark,
+// then the Content-Type header, then a <meta> tag in the first 1024 bytes,
+// and is otherwise guessed.
+func decodeCharset(r io.Reader, contentType string) (io.Reader, Encoding) {
+	r, bom := decodeBOM(r)
+	if bom != "" {
+		return r, Encoding{Name: bom, Source: "bom"}
+	}
+	br := r.(*bufio.Reader)
+	head, _ := br.Peek(charsetSniffBytes)
+
+	var e encoding.Encoding
+	enc := Encoding{}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		if e, enc.Name = charset.Lookup(params["charset"]); e != nil {
+			enc.Source = "header"
+		}
+	}
+	if m := metaCharset.FindSubmatch(head); e == nil && m != nil {
+		if e, enc.Name = charset.Lookup(string(m[1])); e != nil {
+			enc.Source = "meta"
+		}
+	}
+	if e == nil {
+		e, enc.Name, _ = charset.DetermineEncoding(head, "")
+		enc.Source = "fallback"
+	}
+	if enc.Name == "utf-8" {
+		return br, enc
+	}
+	return transform.NewReader(br, e.NewDecoder()), enc
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/atotto/clipboard"
+)
+
+// copyToClipboard writes the scraped data in the given format to the system
+// clipboard.
+func copyToClipboard(data ScrapeData, format string) error {
+	if format == "parquet" {
+		return fmt.Errorf("parquet output cannot be copied to the clipboard")
+	}
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, data, format); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	if err := clipboard.WriteAll(buf.String()); err != nil {
+		return fmt.Errorf("error copying to clipboard: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Inconsistency is a metadata field whose sources on a page disagree, e.g.
+// a <title> that differs from og:title.
+type Inconsistency struct {
+	URL    string            `json:"url,omitempty"`
+	Field  string            `json:"field"`  // title, url or description
+	Values map[string]string `json:"values"` // Value by source, e.g. "og:title"
+}
+
+// consistencyIgnoredTypes are JSON-LD types that describe something other
+// than the page itself, so their name or url is not compared.
+var consistencyIgnoredTypes = []string{"Organization", "WebSite", "BreadcrumbList", "Person", "ImageObject", "SiteNavigationElement"}
+
+// checkConsistency compares the title, canonical URL and description given
+// by the HTML, OpenGraph, Twitter card and JSON-LD metadata of a page, and
+// returns the fields whose sources disagree. Titles and descriptions match
+// when one contains the other ignoring case and spacing, so that "Name |
+// Site" agrees with "Name". URLs are resolved against base and compared
+// without fragment or trailing slash.
+func checkConsistency(doc *goquery.Document, page, base *url.URL) []Inconsistency {
+	pageLD := pageJSONLD(doc)
+	fields := []struct {
+		name    string
+		values  map[string]string
+		matches func(a, b string) bool
+	}{
+		{"title", map[string]string{
+			"title":         doc.Find("title").First().Text(),
+			"og:title":      metaContent(doc, "og:title"),
+			"twitter:title": metaContent(doc, "twitter:title"),
+			"json-ld":       firstNonEmpty(jsonLDString(pageLD, "headline"), jsonLDString(pageLD, "name")),
+		}, textsAgree},
+		{"url", map[string]string{
+			"canonical": resolvedAttr(doc.Find(`link[rel~="canonical"]`).First(), "href", base),
+			"og:url":    resolvedMeta(doc, "og:url", base),
+			"json-ld":   resolvedJSONLD(pageLD, "url", base),
+		}, urlsAgree},
+		{"description", map[string]string{
+			"description":         metaContent(doc, "description"),
+			"og:description":      metaContent(doc, "og:description"),
+			"twitter:description": metaContent(doc, "twitter:description"),
+			"json-ld":             jsonLDString(pageLD, "description"),
+		}, textsAgree},
+	}
+
+	// page is nil when parsing a document with no URL
+	pageURL := ""
+	if page != nil {
+		pageURL = page.String()
+	}
+	var found []Inconsistency
+	for _, f := range fields {
+		var sources []string
+		for source, v := range f.values {
+			if strings.TrimSpace(v) == "" {
+				delete(f.values, source)
+				continue
+			}
+			f.values[source] = strings.Join(strings.Fields(v), " ")
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		if !allAgree(sources, f.values, f.matches) {
+			found = append(found, Inconsistency{URL: pageURL, Field: f.name, Values: f.values})
+		}
+	}
+	return found
+}
+
+// allAgree reports whether every pair of sources has matching values.
+func allAgree(sources []string, values map[string]string, matches func(a, b string) bool) bool {
+	for i, a := range sources {
+		for _, b := range sources[i+1:] {
+			if !matches(values[a], values[b]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// pageJSONLD returns the first JSON-LD object that describes the page
+// rather than its publisher or navigation.
+func pageJSONLD(doc *goquery.Document) map[string]any {
+	for _, obj := range jsonLDObjects(doc) {
+		ignored := false
+		for _, typ := range consistencyIgnoredTypes {
+			if jsonLDHasType(obj, typ) {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			return obj
+		}
+	}
+	return nil
+}
+
+// resolvedAttr returns the attribute of s resolved against base.
+func resolvedAttr(s *goquery.Selection, attr string, base *url.URL) string {
+	v, _ := s.Attr(attr)
+	resolved, _ := resolveURL(base, v)
+	return resolved
+}
+
+// resolvedMeta returns the content of a <meta> URL resolved against base.
+func resolvedMeta(doc *goquery.Document, key string, base *url.URL) string {
+	resolved, _ := resolveURL(base, metaContent(doc, key))
+	return resolved
+}
+
+// resolvedJSONLD returns a JSON-LD URL property resolved against base.
+func resolvedJSONLD(obj map[string]any, key string, base *url.URL) string {
+	resolved, _ := resolveURL(base, jsonLDString(obj, key))
+	return resolved
+}
+
+// textsAgree reports whether one text contains the other, ignoring case.
+func textsAgree(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	return strings.Contains(a, b) || strings.Contains(b, a)
+}
+
+// urlsAgree reports whether two absolute URLs point to the same page.
+func urlsAgree(a, b string) bool {
+	return normalizeConsistencyURL(a) == normalizeConsistencyURL(b)
+}
+
+// normalizeConsistencyURL lowercases the scheme and host and drops the
+// fragment and any trailing slash.
+func normalizeConsistencyURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// String formats the inconsistency for plain-text output.
+func (i Inconsistency) String() string {
+	sources := make([]string, 0, len(i.Values))
+	for source := range i.Values {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	parts := make([]string, len(sources))
+	for n, source := range sources {
+		parts[n] = fmt.Sprintf("%s=%q", source, i.Values[source])
+	}
+	return i.Field + ": " + strings.Join(parts, ", ")
+}
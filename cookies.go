@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookie is a cookie set by the page through a Set-Cookie header.
+type Cookie struct {
+	Name     string     `json:"name"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	MaxAge   int        `json:"max_age,omitempty"`
+	Secure   bool       `json:"secure"`
+	HttpOnly bool       `json:"http_only"`
+	SameSite string     `json:"same_site,omitempty"`
+}
+
+// responseCookies returns the cookies set by resp and by every redirect
+// response that led to it, oldest first.
+func responseCookies(resp *http.Response) []Cookie {
+	var chain []*http.Response
+	for r := resp; r != nil; {
+		chain = append(chain, r)
+		if r.Request == nil {
+			break
+		}
+		r = r.Request.Response
+	}
+
+	var cookies []Cookie
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, c := range chain[i].Cookies() {
+			cookie := Cookie{
+				Name:     c.Name,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				MaxAge:   c.MaxAge,
+				Secure:   c.Secure,
+				HttpOnly: c.HttpOnly,
+				SameSite: sameSiteName(c.SameSite),
+			}
+			if !c.Expires.IsZero() {
+				expires := c.Expires
+				cookie.Expires = &expires
+			}
+			cookies = append(cookies, cookie)
+		}
+	}
+	return cookies
+}
+
+// sameSiteName returns the attribute value for a SameSite mode.
+func sameSiteName(s http.SameSite) string {
+	switch s {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// String formats the cookie for plain-text output.
+func (c Cookie) String() string {
+	parts := []string{c.Name}
+	if c.Domain != "" {
+		parts = append(parts, "domain="+c.Domain)
+	}
+	if c.Expires != nil {
+		parts = append(parts, "expires="+c.Expires.Format(time.RFC3339))
+	}
+	if c.MaxAge != 0 {
+		parts = append(parts, fmt.Sprintf("max-age=%d", c.MaxAge))
+	}
+	if c.Secure {
+		parts = append(parts, "Secure")
+	}
+	if c.HttpOnly {
+		parts = append(parts, "HttpOnly")
+	}
+	if c.SameSite != "" {
+		parts = append(parts, "SameSite="+c.SameSite)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stateSaveInterval is how many pages are crawled between saves of the
+// crawl state file.
+const stateSaveInterval = 10
+
+// queueItem is a URL waiting to be crawled, the depth it was found at, and
+// how many links deep it is into its own host.
+type queueItem struct {
+	URL       string `json:"url"`
+	Depth     int    `json:"depth"`
+	HostDepth int    `json:"host_depth,omitempty"`
+}
+
+// crawlState is everything needed to resume a crawl: the start URL, the
+// pending frontier, the set of URLs already queued or fetched, those whose
+// data was kept, and the data gathered so far. It is kept separate from the
+// crawl loop so it can be written to disk.
+type crawlState struct {
+	Start   string          `json:"start"`
+	Pending []queueItem     `json:"pending"`
+	Visited map[string]bool `json:"visited"`
+	Scraped map[string]bool `json:"scraped,omitempty"`
+	Fetched int             `json:"fetched"`
+	Data    ScrapeData      `json:"data"`
+}
+
+// newCrawlState returns an empty state with start queued at depth 0.
+func newCrawlState(start string) *crawlState {
+	s := &crawlState{Start: start, Visited: make(map[string]bool), Scraped: make(map[string]bool)}
+	s.push(start, 0, 0)
+	return s
+}
+
+// push queues a URL unless it has already been seen, reporting whether it
+// was queued.
+func (s *crawlState) push(u string, depth, hostDepth int) bool {
+	if s.Visited[u] {
+		return false
+	}
+	s.Visited[u] = true
+	s.Pending = append(s.Pending, queueItem{URL: u, Depth: depth, HostDepth: hostDepth})
+	return true
+}
+
+// pop removes and returns the URL in the frontier at the index chosen by
+// next, which is given the frontier's length.
+func (s *crawlState) pop(next func(n int) int) (queueItem, bool) {
+	if len(s.Pending) == 0 {
+		return queueItem{}, false
+	}
+	i := next(len(s.Pending))
+	item := s.Pending[i]
+	if i == 0 {
+		s.Pending = s.Pending[1:]
+	} else {
+		s.Pending = append(s.Pending[:i], s.Pending[i+1:]...)
+	}
+	return item, true
+}
+
+// crawlOrderPicker returns the frontier index to crawl next for a
+// -crawl-order: the oldest entry for bfs, the newest for dfs, or a random
+// one drawn from rng.
+func crawlOrderPicker(order string, rng *rand.Rand) (func(n int) int, error) {
+	switch order {
+	case "", "bfs":
+		return func(n int) int { return 0 }, nil
+	case "dfs":
+		return func(n int) int { return n - 1 }, nil
+	case "random":
+		return rng.Intn, nil
+	}
+	return nil, fmt.Errorf("unknown -crawl-order %q, want bfs, dfs or random", order)
+}
+
+// save writes the state to path, replacing any previous file atomically.
+func (s *crawlState) save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("error encoding crawl state: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("error writing crawl state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing crawl state: %v", err)
+	}
+	return nil
+}
+
+// loadCrawlState reads a state file written by save.
+func loadCrawlState(path string) (*crawlState, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading crawl state: %v", err)
+	}
+	s := &crawlState{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("error decoding crawl state: %v", err)
+	}
+	if s.Visited == nil {
+		s.Visited = make(map[string]bool)
+	}
+	if s.Scraped == nil {
+		s.Scraped = make(map[string]bool)
+	}
+	return s, nil
+}
+
+// crawl scrapes start and follows links on the same host, or on the
+// domains in opts.AllowDomains, up to opts.Depth, merging the data from every
+// page. Hosts in opts.HostDepth are additionally limited to that many links
+// deep from where the crawl first reached them. At most opts.MaxURLs pages
+// are fetched when it is set. When opts.StateFile is set the state is saved
+// there periodically, and opts.Resume continues from a saved state. While
+// opts.PauseFile exists no new pages are started.
+//
+// When ctx is cancelled no new pages are started, the page in flight is given
+// a short grace period, and the state is saved before crawl returns the data
+// gathered so far with errInterrupted.
+func crawl(ctx context.Context, start string, opts Options) (ScrapeData, error) {
+	return crawlTo(ctx, start, opts, nil)
+}
+
+// crawlTo is like crawl, but when flush is non-nil and opts.FlushEvery is
+// set, the data gathered is passed to flush every opts.FlushEvery pages and
+// dropped from memory, with the remainder flushed before crawlTo returns.
+// Data is always flushed before the state file is saved, so a resumed crawl
+// never skips data that was not written.
+func crawlTo(ctx context.Context, start string, opts Options, flush func(ScrapeData) error) (ScrapeData, error) {
+	var state *crawlState
+	if opts.Resume {
+		if opts.StateFile == "" {
+			return ScrapeData{}, fmt.Errorf("-resume requires -state")
+		}
+		var err error
+		if state, err = loadCrawlState(opts.StateFile); err != nil {
+			return ScrapeData{}, err
+		}
+		log.Printf("Resuming crawl: %d pending, %d seen", len(state.Pending), len(state.Visited))
+	} else {
+		u, err := url.Parse(start)
+		if err != nil {
+			return ScrapeData{}, fmt.Errorf("error parsing URL: %v", err)
+		}
+		state = newCrawlState(normalizeURL(u))
+	}
+
+	startURL, err := url.Parse(state.Start)
+	if err != nil {
+		return ScrapeData{}, fmt.Errorf("error parsing URL: %v", err)
+	}
+
+	reqCtx, cancel := withGrace(ctx)
+	defer cancel()
+
+	flushData := func() error {
+		if flush == nil || opts.FlushEvery <= 0 || (state.Data.URL == "" && len(state.Data.Errors) == 0) {
+			return nil
+		}
+		if err := flush(state.Data); err != nil {
+			return err
+		}
+		state.Data = ScrapeData{}
+		return nil
+	}
+
+	seed := opts.Seed
+	if seed == 0 && (opts.SampleRate < 1 || opts.CrawlOrder == "random") {
+		seed = time.Now().UnixNano()
+		log.Printf("Crawling with -seed %d", seed)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	next, err := crawlOrderPicker(opts.CrawlOrder, rng)
+	if err != nil {
+		return ScrapeData{}, err
+	}
+	sample := newSampler(opts.SampleRate, rng)
+	pages := 0
+	interrupted := false
+	for {
+		if ctx.Err() != nil {
+			interrupted = true
+			break
+		}
+		if pauseRequested(opts.PauseFile) {
+			// Persist everything first, so a paused crawl can also be
+			// stopped and continued later with -resume
+			if err := flushData(); err != nil {
+				return state.Data, err
+			}
+			if opts.StateFile != "" {
+				if err := state.save(opts.StateFile); err != nil {
+					return state.Data, err
+				}
+			}
+			log.Printf("Paused: %s; remove %s to resume", state.progress(), opts.PauseFile)
+			if !waitForResume(ctx, opts.PauseFile) {
+				interrupted = true
+				break
+			}
+			log.Printf("Resumed: %s", state.progress())
+		}
+		if opts.MaxURLs > 0 && state.Fetched >= opts.MaxURLs {
+			if len(state.Pending) > 0 {
+				log.Printf("Reached -max-urls %d, skipped %d queued URLs", opts.MaxURLs, len(state.Pending))
+			}
+			break
+		}
+		item, ok := state.pop(next)
+		if !ok {
+			break
+		}
+		if opts.RespectRobots && !robotsAllowed(reqCtx, item.URL, opts) {
+			log.Printf("Skipping %s: disallowed by robots.txt", item.URL)
+			continue
+		}
+
+		sampled := sample()
+		pageOpts := opts
+		if !sampled {
+			pageOpts = discoveryOptions(opts)
+		}
+		if item.URL != state.Start {
+			pageOpts = followOptions(pageOpts)
+		}
+		// The crawl queues canonical URLs itself so each is fetched once
+		pageOpts.FollowCanonical = false
+		data, err := scrapePage(reqCtx, item.URL, pageOpts)
+		if err != nil && reqCtx.Err() != nil {
+			// Cancelled mid-request: keep the page queued for -resume
+			state.Pending = append([]queueItem{item}, state.Pending...)
+			interrupted = true
+			break
+		}
+		state.Fetched++
+		variant := false
+		if err == nil && opts.FollowCanonical {
+			if target, ok := canonicalTarget(data); ok {
+				// A variant of another page: crawl that page instead, at the
+				// same depth. If it is already queued, or was itself dropped
+				// as a variant, this page is kept so neither goes missing.
+				if state.push(target, item.Depth, item.HostDepth) {
+					log.Printf("%s: canonical is %s, crawling it instead", item.URL, target)
+					variant = true
+				} else if state.Scraped[target] {
+					log.Printf("%s: canonical %s was already crawled, skipping", item.URL, target)
+					variant = true
+				}
+			}
+		}
+		if variant {
+			// Treated like a redirect: neither its data nor its links are kept
+		} else if err != nil {
+			log.Printf("Failed to scrape %s: %v", item.URL, err)
+			state.Data.Errors = append(state.Data.Errors, newPageError(item.URL, err))
+		} else if sampled {
+			state.Data = mergeData(state.Data, data)
+			if opts.CountOnly && !opts.OnlyErrors {
+				opts.printf("%s: %s\n", item.URL, countSummary(data))
+			}
+			if len(data.Profile) > 0 {
+				log.Printf("%s: %s", item.URL, formatProfile(data.Profile))
+			}
+		}
+		if err == nil && !variant {
+			state.Scraped[item.URL] = true
+			if opts.LinkGraph {
+				state.Data = recordLinks(state.Data, item.URL, data.Links, startURL, opts)
+			}
+			if opts.ESDocuments && sampled {
+				state.Data.ESDocuments = append(state.Data.ESDocuments, newESDocument(data))
+			}
+			if opts.ParquetRows && sampled {
+				state.Data.ParquetRows = append(state.Data.ParquetRows, parquetRows(data)...)
+			}
+			if item.Depth < opts.Depth {
+				from, _ := url.Parse(item.URL)
+				for _, link := range data.Links {
+					if opts.SkipNofollow && link.hasRel("nofollow") {
+						continue
+					}
+					u, err := url.Parse(link.URL)
+					if err != nil || !crawlHost(u, startURL, opts) {
+						continue
+					}
+					hostDepth := 0
+					if from != nil && u.Host == from.Host {
+						hostDepth = item.HostDepth + 1
+					}
+					if limit, ok := hostDepthLimit(u, opts); ok && hostDepth > limit {
+						continue
+					}
+					state.push(normalizeURL(u), item.Depth+1, hostDepth)
+				}
+			}
+		}
+
+		pages++
+		if opts.FlushEvery > 0 && pages%opts.FlushEvery == 0 {
+			if err := flushData(); err != nil {
+				return state.Data, err
+			}
+		}
+		if opts.StateFile != "" && pages%stateSaveInterval == 0 {
+			if err := flushData(); err != nil {
+				return state.Data, err
+			}
+			if err := state.save(opts.StateFile); err != nil {
+				return state.Data, err
+			}
+		}
+	}
+
+	if err := flushData(); err != nil {
+		return state.Data, err
+	}
+	if opts.StateFile != "" {
+		if err := state.save(opts.StateFile); err != nil {
+			return state.Data, err
+		}
+	}
+	if totalRetries != nil {
+		log.Printf("Retry budget: %s", totalRetries)
+	}
+	if interrupted {
+		log.Printf("Crawl interrupted after %d pages, %d still queued", pages, len(state.Pending))
+		return state.Data, errInterrupted
+	}
+	return state.Data, nil
+}
+
+// recordLinks adds the edges from page to each crawlable page it links to
+// into data's link graph.
+func recordLinks(data ScrapeData, page string, links []Link, start *url.URL, opts Options) ScrapeData {
+	if data.LinkGraph == nil {
+		data.LinkGraph = make(map[string][]string)
+	}
+	seen := make(map[string]bool)
+	targets := data.LinkGraph[page]
+	for _, link := range links {
+		u, err := url.Parse(link.URL)
+		if err != nil || !crawlHost(u, start, opts) {
+			continue
+		}
+		target := normalizeURL(u)
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	data.LinkGraph[page] = targets
+	return data
+}
+
+// crawlHost reports whether the crawl may follow links to u: it must be on
+// the start host or on one of opts.AllowDomains or their subdomains.
+func crawlHost(u, start *url.URL, opts Options) bool {
+	if u.Host == start.Host {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range opts.AllowDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostDepthLimit returns the -host-depth limit for u's host, if it has one.
+func hostDepthLimit(u *url.URL, opts Options) (int, bool) {
+	if limit, ok := opts.HostDepth[strings.ToLower(u.Host)]; ok {
+		return limit, true
+	}
+	limit, ok := opts.HostDepth[strings.ToLower(u.Hostname())]
+	return limit, ok
+}
+
+// parseHostDepth parses a -host-depth value given as host=N.
+func parseHostDepth(s string) (string, int, error) {
+	host, n, ok := strings.Cut(s, "=")
+	depth, err := strconv.Atoi(strings.TrimSpace(n))
+	host = strings.TrimSpace(host)
+	if !ok || host == "" || err != nil || depth < 0 {
+		return "", 0, fmt.Errorf("invalid -host-depth %q, want host=N", s)
+	}
+	return strings.ToLower(host), depth, nil
+}
+
+// crawlToFile crawls from url, appending the results to filename every
+// opts.FlushEvery pages, and returns the pages that failed. The file is
+// appended to when resuming a crawl. With opts.OnlyErrors only the failures
+// are written.
+func crawlToFile(ctx context.Context, url string, opts Options, filename, format string) ([]PageError, error) {
+	if format != "text" && format != "jsonl" && format != "es-bulk" {
+		return nil, fmt.Errorf("-flush-every needs the text, jsonl or es-bulk format, not %s", format)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.Resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(filename, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file: %v", err)
+	}
+	defer file.Close()
+
+	var failures []PageError
+	flush := func(data ScrapeData) error {
+		failures = append(failures, data.Errors...)
+		writer := bufio.NewWriter(file)
+		if opts.OnlyErrors {
+			if format == "text" {
+				for _, e := range data.Errors {
+					fmt.Fprintln(writer, e)
+				}
+				return writer.Flush()
+			}
+			if len(data.Errors) == 0 {
+				return nil
+			}
+			data = ScrapeData{Errors: data.Errors}
+		}
+		if err := writeOutput(writer, data, format); err != nil {
+			return fmt.Errorf("error writing output: %v", err)
+		}
+		return writer.Flush()
+	}
+	_, err = crawlTo(ctx, url, opts, flush)
+	return failures, err
+}
+
+// normalizeURL returns u without its fragment so the same page is only
+// crawled once.
+func normalizeURL(u *url.URL) string {
+	c := *u
+	c.Fragment = ""
+	return c.String()
+}
+
+// mergeData appends the contents of b to a.
+func mergeData(a, b ScrapeData) ScrapeData {
+	a.Truncated = a.Truncated || b.Truncated
+	if a.URL == "" {
+		a.URL, a.Status, a.Title, a.ScrapedAt = b.URL, b.Status, b.Title, b.ScrapedAt
+		a.Author, a.Publisher = b.Author, b.Publisher
+	}
+	a.Links = append(a.Links, b.Links...)
+	a.Texts = append(a.Texts, b.Texts...)
+	a.Images = append(a.Images, b.Images...)
+	a.InlineImages = append(a.InlineImages, b.InlineImages...)
+	a.DownloadedImages = mergeDownloadedImages(a.DownloadedImages, b.DownloadedImages)
+	if a.LastModified == nil {
+		a.LastModified = b.LastModified
+	}
+	if a.RefreshURL == "" {
+		a.RefreshURL = b.RefreshURL
+	}
+	if a.Encoding == nil {
+		a.Encoding = b.Encoding
+	}
+	for tag, entries := range b.Accessibility {
+		if a.Accessibility == nil {
+			a.Accessibility = make(map[string][]A11yEntry)
+		}
+		a.Accessibility[tag] = append(a.Accessibility[tag], entries...)
+	}
+	a.IFrames = append(a.IFrames, b.IFrames...)
+	a.MainText = append(a.MainText, b.MainText...)
+	a.Headings = append(a.Headings, b.Headings...)
+	a.Tables = append(a.Tables, b.Tables...)
+	a.LocalizedValues = append(a.LocalizedValues, b.LocalizedValues...)
+	a.Prices = append(a.Prices, b.Prices...)
+	a.FAQ = append(a.FAQ, b.FAQ...)
+	a.Videos = append(a.Videos, b.Videos...)
+	a.Events = append(a.Events, b.Events...)
+	a.Profile = mergeProfiles(a.Profile, b.Profile)
+	if a.Breadcrumbs == nil {
+		a.Breadcrumbs = b.Breadcrumbs
+	}
+	a.SiteTree = mergeSiteTree(a.SiteTree, b.SiteTree)
+	a.Templates = append(a.Templates, b.Templates...)
+	a.Inconsistencies = append(a.Inconsistencies, b.Inconsistencies...)
+	a.Times = append(a.Times, b.Times...)
+	a.Warnings = append(a.Warnings, b.Warnings...)
+	a.Errors = append(a.Errors, b.Errors...)
+	a.Cookies = append(a.Cookies, b.Cookies...)
+	a.BrokenAnchors = append(a.BrokenAnchors, b.BrokenAnchors...)
+	a.ResourceHints = append(a.ResourceHints, b.ResourceHints...)
+	if a.Recipe == nil {
+		a.Recipe = b.Recipe
+	}
+	if a.Product == nil {
+		a.Product = b.Product
+	}
+	if a.Viewport == nil {
+		a.Viewport = b.Viewport
+	}
+	if a.Rating == nil {
+		a.Rating = b.Rating
+	}
+	if b.PageWeight != nil {
+		if a.PageWeight == nil {
+			a.PageWeight = &PageWeight{}
+		}
+		a.PageWeight.add(b.PageWeight)
+	}
+	if b.DOMMetrics != nil {
+		if a.DOMMetrics == nil {
+			a.DOMMetrics = &DOMMetrics{}
+		}
+		a.DOMMetrics.add(b.DOMMetrics)
+	}
+	for lang, href := range b.Alternates {
+		if a.Alternates == nil {
+			a.Alternates = make(map[string]string)
+		}
+		if _, ok := a.Alternates[lang]; !ok {
+			a.Alternates[lang] = href
+		}
+	}
+	for src, dims := range b.ImageDimensions {
+		if a.ImageDimensions == nil {
+			a.ImageDimensions = make(map[string]Dimensions)
+		}
+		a.ImageDimensions[src] = dims
+	}
+	for host, n := range b.ThirdPartyScripts {
+		if a.ThirdPartyScripts == nil {
+			a.ThirdPartyScripts = make(map[string]int)
+		}
+		a.ThirdPartyScripts[host] += n
+	}
+	a.ESDocuments = append(a.ESDocuments, b.ESDocuments...)
+	a.ParquetRows = append(a.ParquetRows, b.ParquetRows...)
+	for page, targets := range b.LinkGraph {
+		if a.LinkGraph == nil {
+			a.LinkGraph = make(map[string][]string)
+		}
+		a.LinkGraph[page] = append(a.LinkGraph[page], targets...)
+	}
+	for kind, urls := range b.Media {
+		if a.Media == nil {
+			a.Media = make(map[string][]string)
+		}
+		a.Media[kind] = append(a.Media[kind], urls...)
+	}
+	for name, values := range b.Selections {
+		if a.Selections == nil {
+			a.Selections = make(map[string][]string)
+		}
+		a.Selections[name] = append(a.Selections[name], values...)
+	}
+	return a
+}
+
+// newSampler returns a function that decides for each crawled page whether
+// its data is kept, true for about rate of the calls. The sequence is
+// deterministic for a given -seed of rng.
+func newSampler(rate float64, rng *rand.Rand) func() bool {
+	if rate >= 1 {
+		return func() bool { return true }
+	}
+	return func() bool { return rng.Float64() < rate }
+}
+
+// discoveryOptions returns opts for a page fetched only to find links:
+// the features that make extra requests are turned off.
+func discoveryOptions(opts Options) Options {
+	opts.ImageDims = false
+	opts.PageWeight = false
+	opts.DownloadImages = ""
+	opts.ExpandURLs = false
+	opts.FollowIFrames = false
+	return opts
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InlineImage is an image embedded in the page as a data: URI. Only its
+// type and decoded size are kept in the output, not the payload.
+type InlineImage struct {
+	MIME string `json:"mime"`
+	Size int    `json:"size"`           // Decoded size in bytes
+	File string `json:"file,omitempty"` // Where it was saved, with -download-images
+	data []byte
+}
+
+// imageExtensions maps image MIME types to the extension they are saved
+// with; others fall back to the mime package.
+var imageExtensions = map[string]string{
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/avif":               ".avif",
+	"image/svg+xml":            ".svg",
+	"image/bmp":                ".bmp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/tiff":               ".tiff",
+}
+
+// parseDataImage decodes a data: URI holding an image, base64 or
+// percent-encoded. It returns false for other sources and malformed URIs.
+func parseDataImage(src string) (InlineImage, bool) {
+	rest, ok := cutPrefixFold(strings.TrimSpace(src), "data:")
+	if !ok {
+		return InlineImage{}, false
+	}
+	header, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return InlineImage{}, false
+	}
+	params := strings.Split(header, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return InlineImage{}, false
+	}
+
+	var data []byte
+	var err error
+	if strings.EqualFold(strings.TrimSpace(params[len(params)-1]), "base64") {
+		// Line breaks and spaces are common in hand-written data URIs
+		payload = strings.Join(strings.Fields(payload), "")
+		payload, err = url.PathUnescape(payload)
+		if err == nil {
+			data, err = base64.StdEncoding.DecodeString(payload)
+			if err != nil {
+				data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+			}
+		}
+	} else {
+		var s string
+		s, err = url.PathUnescape(payload)
+		data = []byte(s)
+	}
+	if err != nil {
+		return InlineImage{}, false
+	}
+	return InlineImage{MIME: mediaType, Size: len(data), data: data}, true
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring ASCII case.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// fileName returns the file the image is saved as: a hash of its content,
+// so the same inline image on many pages is saved once, and the extension
+// for its MIME type.
+func (img InlineImage) fileName() string {
+	sum := sha1.Sum(img.data)
+	ext, ok := imageExtensions[img.MIME]
+	if !ok {
+		ext = ".bin"
+		if exts, _ := mime.ExtensionsByType(img.MIME); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return "inline-" + hex.EncodeToString(sum[:8]) + ext
+}
+
+// saveInlineImages writes the decoded images into dir and records each
+// file in the image's File field. Files that already exist are kept.
+func saveInlineImages(images []InlineImage, dir string) {
+	if len(images) == 0 {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Failed to create %s: %v", dir, err)
+		return
+	}
+	for i, img := range images {
+		dest := filepath.Join(dir, img.fileName())
+		if _, err := os.Stat(dest); err != nil {
+			if err := os.WriteFile(dest, img.data, 0644); err != nil {
+				log.Printf("Failed to save inline %s image: %v", img.MIME, err)
+				continue
+			}
+		}
+		images[i].File = dest
+	}
+}
+
+// String formats the image for plain-text output in place of its payload.
+func (img InlineImage) String() string {
+	s := fmt.Sprintf("[data-uri image] %s, %s", img.MIME, formatBytes(int64(img.Size)))
+	if img.File != "" {
+		s += " -> " + img.File
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// timestampLayouts are the date formats accepted by parseTimestamp.
+var timestampLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// parseTimestamp parses s in any of the common date formats used in HTML
+// metadata and HTTP headers.
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := http.ParseTime(s); err == nil {
+		return t, true
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// extractLastModified returns the modification date declared in the
+// document's meta tags or JSON-LD.
+func extractLastModified(doc *goquery.Document) *time.Time {
+	candidates := []string{
+		metaContent(doc, "article:modified_time"),
+		metaContent(doc, "og:updated_time"),
+		metaContent(doc, "last-modified"),
+	}
+	for _, obj := range jsonLDObjects(doc) {
+		candidates = append(candidates, jsonLDString(obj, "dateModified"))
+	}
+	for _, c := range candidates {
+		if t, ok := parseTimestamp(c); ok {
+			return &t
+		}
+	}
+	return nil
+}
+
+// TimeEntry is a <time> element: its datetime attribute, its text, and the
+// time they describe when it could be parsed.
+type TimeEntry struct {
+	Datetime string     `json:"datetime,omitempty"`
+	Text     string     `json:"text,omitempty"`
+	Time     *time.Time `json:"time,omitempty"`
+}
+
+// extractTimes collects every <time> element. Without a datetime attribute
+// the element's text is its machine-readable value, as in HTML.
+func extractTimes(doc *goquery.Document) []TimeEntry {
+	var entries []TimeEntry
+	doc.Find("time").Each(func(i int, s *goquery.Selection) {
+		dt, hasDatetime := s.Attr("datetime")
+		entry := TimeEntry{
+			Datetime: strings.TrimSpace(dt),
+			Text:     strings.Join(strings.Fields(s.Text()), " "),
+		}
+		value := entry.Text
+		if hasDatetime {
+			value = entry.Datetime
+		}
+		if t, ok := parseTimestamp(value); ok {
+			entry.Time = &t
+		}
+		if entry.Datetime != "" || entry.Text != "" {
+			entries = append(entries, entry)
+		}
+	})
+	return entries
+}
+
+// String formats the entry for plain-text output.
+func (e TimeEntry) String() string {
+	s := e.Text
+	if e.Datetime != "" && e.Datetime != e.Text {
+		s = strings.TrimSpace(s + " [" + e.Datetime + "]")
+	}
+	if e.Time != nil {
+		s += " = " + e.Time.Format(time.RFC3339)
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// contentHash returns a SHA-256 hash of the extracted text, used to tell
+// whether a page's content changed between scrapes.
+func contentHash(data ScrapeData) string {
+	h := sha256.New()
+	for _, text := range data.Texts {
+		io.WriteString(h, text.Text)
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadResult reads a result previously saved with -format json.
+func loadResult(path string) (ScrapeData, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ScrapeData{}, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	var data ScrapeData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return ScrapeData{}, fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// diffTexts returns the paragraphs present in cur but not prev, and those
+// present in prev but not cur.
+func diffTexts(prev, cur ScrapeData) (added, removed []string) {
+	before := make(map[string]bool)
+	for _, text := range prev.Texts {
+		before[text.Text] = true
+	}
+	after := make(map[string]bool)
+	for _, text := range cur.Texts {
+		after[text.Text] = true
+		if !before[text.Text] {
+			added = append(added, text.Text)
+		}
+	}
+	for _, text := range prev.Texts {
+		if !after[text.Text] {
+			removed = append(removed, text.Text)
+		}
+	}
+	return added, removed
+}
+
+// writeDiff reports whether the content changed since prev, listing the
+// added and removed paragraphs if it did.
+func writeDiff(w io.Writer, prev, cur ScrapeData) {
+	prevHash := prev.ContentHash
+	if prevHash == "" {
+		prevHash = contentHash(prev)
+	}
+	if prevHash == cur.ContentHash {
+		fmt.Fprintln(w, "Content unchanged since previous scrape.")
+		return
+	}
+
+	added, removed := diffTexts(prev, cur)
+	fmt.Fprintf(w, "Content changed since previous scrape (%d added, %d removed paragraphs).\n", len(added), len(removed))
+	for _, text := range added {
+		fmt.Fprintf(w, "+ %s\n", text)
+	}
+	for _, text := range removed {
+		fmt.Fprintf(w, "- %s\n", text)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsCacheTTL is how long resolved addresses are reused.
+const dnsCacheTTL = 5 * time.Minute
+
+// dnsEntry is a cached lookup result.
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// dnsCache resolves host names for the HTTP transport, caching lookups and
+// applying -resolve overrides.
+type dnsCache struct {
+	mu        sync.Mutex
+	entries   map[string]dnsEntry
+	overrides map[string]string // Host to pinned IP
+	dialer    *net.Dialer
+}
+
+// newDNSCache returns a cache that pins the hosts in overrides.
+func newDNSCache(overrides map[string]string) *dnsCache {
+	return &dnsCache{
+		entries:   make(map[string]dnsEntry),
+		overrides: overrides,
+		dialer:    &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+}
+
+// parseResolve parses a -resolve value of the form host:ip.
+func parseResolve(s string) (string, string, error) {
+	host, ip, ok := strings.Cut(s, ":")
+	ip = strings.Trim(ip, "[]")
+	if !ok || host == "" || net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf("invalid -resolve %q, want host:ip", s)
+	}
+	return strings.ToLower(host), ip, nil
+}
+
+// lookup returns the addresses for host, from the overrides or the cache if
+// possible.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	if ip, ok := c.overrides[strings.ToLower(host)]; ok {
+		return []string{ip}, nil
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(dnsCacheTTL)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// dialContext resolves addr through the cache and connects to the first
+// address that accepts the connection.
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotEscape makes s safe inside a double-quoted DOT string.
+var dotEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// writeDot writes the link graph of a crawl as a GraphViz digraph, with a
+// node per page and an edge per link between pages. Without a crawl graph,
+// the page's own links are used. With -breadcrumbs the site tree is drawn
+// instead.
+func writeDot(w io.Writer, data ScrapeData) error {
+	if len(data.SiteTree) > 0 {
+		return writeSiteTreeDot(w, data.SiteTree)
+	}
+	graph := data.LinkGraph
+	if len(graph) == 0 && data.URL != "" {
+		graph = map[string][]string{data.URL: nil}
+		for _, link := range data.Links {
+			graph[data.URL] = append(graph[data.URL], link.URL)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph crawl {")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	for _, page := range sortedKeys(graph) {
+		fmt.Fprintf(bw, "  \"%s\";\n", dotEscape.Replace(page))
+	}
+	for _, page := range sortedKeys(graph) {
+		for _, target := range graph[page] {
+			fmt.Fprintf(bw, "  \"%s\" -> \"%s\";\n", dotEscape.Replace(page), dotEscape.Replace(target))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
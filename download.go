@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DownloadedImage is one distinct image saved by -download-images: the
+// SHA-256 of its content, the file it was saved as, and every URL that
+// served that content.
+type DownloadedImage struct {
+	SHA256 string   `json:"sha256"`
+	File   string   `json:"file"`
+	URLs   []string `json:"urls"`
+}
+
+// savedImages maps the content hash of every image saved during the run to
+// its file, so CDN variants of the same image are only kept once.
+var savedImages = struct {
+	sync.Mutex
+	files map[string]string
+}{files: make(map[string]string)}
+
+// storeImage keeps the downloaded file at path as dest, unless an image with
+// the same content was already saved; then path is removed and that file is
+// returned instead. A path that is already dest, from an earlier run, is
+// never removed. It returns the file holding the image and its SHA-256.
+func storeImage(path, dest string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return "", "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	// The rename happens under the lock, so the hash is only registered
+	// for a file that exists and a concurrent duplicate still finds it
+	savedImages.Lock()
+	defer savedImages.Unlock()
+	if existing, ok := savedImages.files[sum]; ok && existing != dest {
+		if path != dest {
+			os.Remove(path)
+		}
+		return existing, sum, nil
+	}
+	if path != dest {
+		if err := os.Rename(path, dest); err != nil {
+			return "", "", err
+		}
+	}
+	savedImages.files[sum] = dest
+	return dest, sum, nil
+}
+
+// imageFileName returns the file an image URL is saved as: its base name
+// prefixed with a short hash of the URL, so different images with the same
+// name don't collide and re-runs pick the same file.
+func imageFileName(u *url.URL) string {
+	sum := sha1.Sum([]byte(u.String()))
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = "image"
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return hex.EncodeToString(sum[:4]) + "-" + name
+}
+
+// downloadImage saves the image at u to dest. The body is written to
+// dest+".part" and renamed once complete, with the response ETag kept beside
+// it. When a partial file and ETag exist, only the remaining bytes are
+// requested, using If-Range so a changed image is fetched again in full.
+// Files that already exist are not fetched again. Images whose content was
+// already saved are not kept; the file and SHA-256 of the content are
+// returned.
+func downloadImage(ctx context.Context, u, dest string) (string, string, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return storeImage(dest, dest)
+	}
+	part, etagFile := dest+".part", dest+".part.etag"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if userAgents != nil {
+		req.Header.Set("User-Agent", userAgents.pick())
+	}
+	var offset int64
+	if info, err := os.Stat(part); err == nil && info.Size() > 0 {
+		if etag, err := os.ReadFile(etagFile); err == nil && len(etag) > 0 {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", string(etag))
+		}
+	}
+
+	resp, err := doRequest(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// No resume, or the image changed since the partial download
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file doesn't match the image; start over next run
+		os.Remove(part)
+		os.Remove(etagFile)
+		return "", "", &statusError{Code: resp.StatusCode}
+	default:
+		return "", "", &statusError{Code: resp.StatusCode}
+	}
+
+	// Only strong ETags can validate a range request
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		if err := os.WriteFile(etagFile, []byte(etag), 0644); err != nil {
+			return "", "", err
+		}
+	} else {
+		os.Remove(etagFile)
+	}
+
+	file, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", "", err
+	}
+	os.Remove(etagFile)
+	return storeImage(part, dest)
+}
+
+// downloadImages saves every image in srcs into dir, with at most
+// concurrency downloads, and so open files, at a time. It returns the
+// distinct images saved, in the order of srcs, each with the URLs that
+// served its content; duplicates are collapsed into one file.
+func downloadImages(ctx context.Context, srcs []string, base *url.URL, dir string, concurrency int) []DownloadedImage {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Failed to create %s: %v", dir, err)
+		return nil
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type saved struct {
+		order     int
+		url       string
+		file, sum string
+	}
+	var mu sync.Mutex
+	var results []saved
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	seen := make(map[string]bool)
+	for i, src := range srcs {
+		abs, ok := resolveURL(base, src)
+		if !ok || seen[abs] {
+			continue
+		}
+		// The same image twice would write to the same file
+		seen[abs] = true
+		u, err := url.Parse(abs)
+		if err != nil {
+			continue
+		}
+		dest := filepath.Join(dir, imageFileName(u))
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			file, sum, err := downloadImage(ctx, abs, dest)
+			if err != nil {
+				log.Printf("Failed to download %s: %v", abs, err)
+				return
+			}
+			mu.Lock()
+			results = append(results, saved{i, abs, file, sum})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(a, b int) bool { return results[a].order < results[b].order })
+	var images []DownloadedImage
+	index := make(map[string]int)
+	for _, r := range results {
+		if j, ok := index[r.sum]; ok {
+			images[j].URLs = append(images[j].URLs, r.url)
+			continue
+		}
+		index[r.sum] = len(images)
+		images = append(images, DownloadedImage{SHA256: r.sum, File: r.file, URLs: []string{r.url}})
+	}
+	if dups := len(results) - len(images); dups > 0 {
+		log.Printf("Collapsed %d duplicate images into %d files", dups, len(images))
+	}
+	return images
+}
+
+// mergeDownloadedImages adds the images of b to a, merging the URLs of
+// images with the same content.
+func mergeDownloadedImages(a, b []DownloadedImage) []DownloadedImage {
+	for _, img := range b {
+		found := false
+		for i := range a {
+			if a[i].SHA256 == img.SHA256 {
+				for _, u := range img.URLs {
+					if !slices.Contains(a[i].URLs, u) {
+						a[i].URLs = append(a[i].URLs, u)
+					}
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, DownloadedImage{SHA256: img.SHA256, File: img.File, URLs: append([]string(nil), img.URLs...)})
+		}
+	}
+	return a
+}
+
+// duplicateImages returns how many downloaded URLs were collapsed into an
+// image saved for another URL.
+func duplicateImages(images []DownloadedImage) int {
+	n := 0
+	for _, img := range images {
+		if len(img.URLs) > 1 {
+			n += len(img.URLs) - 1
+		}
+	}
+	return n
+}
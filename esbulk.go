@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// esIndex is the target index named in -format es-bulk action lines.
+var esIndex = "scrape"
+
+// ESDocument is the document indexed for each page with -format es-bulk.
+type ESDocument struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	Text      string    `json:"text"`
+	ScrapedAt time.Time `json:"scraped_at"`
+}
+
+// newESDocument returns the document indexed for the page scraped into data.
+func newESDocument(data ScrapeData) ESDocument {
+	texts := make([]string, len(data.Texts))
+	for i, t := range data.Texts {
+		texts[i] = t.Text
+	}
+	return ESDocument{URL: data.URL, Title: data.Title, Text: strings.Join(texts, "\n\n"), ScrapedAt: data.ScrapedAt}
+}
+
+// writeESBulk writes data as an Elasticsearch/OpenSearch _bulk request body:
+// an index action line followed by the document, for each page of a crawl
+// or for the single page scraped. The document _id is a hash of the page
+// URL, so indexing a page again replaces its earlier document.
+func writeESBulk(w io.Writer, data ScrapeData) error {
+	docs := data.ESDocuments
+	if len(docs) == 0 {
+		if data.URL == "" {
+			// Nothing but errors, as in a -flush-every chunk of failures
+			return nil
+		}
+		docs = []ESDocument{newESDocument(data)}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, doc := range docs {
+		sum := sha1.Sum([]byte(doc.URL))
+		action := map[string]map[string]string{
+			"index": {"_index": esIndex, "_id": hex.EncodeToString(sum[:])},
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Event is a schema.org Event or one of its subtypes, such as MusicEvent.
+type Event struct {
+	Name           string     `json:"name"`
+	Start          *time.Time `json:"start,omitempty"`
+	End            *time.Time `json:"end,omitempty"`
+	Location       string     `json:"location,omitempty"` // Place name and address, or the URL of a virtual location
+	Price          string     `json:"price,omitempty"`
+	Currency       string     `json:"currency,omitempty"`
+	Amount         *float64   `json:"amount,omitempty"` // Price as a number
+	Status         string     `json:"status,omitempty"` // e.g. EventScheduled, EventCancelled
+	AttendanceMode string     `json:"attendance_mode,omitempty"`
+	URL            string     `json:"url,omitempty"`
+	Source         string     `json:"source"` // json-ld or microdata
+}
+
+// isEventType reports whether a schema.org type names an Event, whose
+// subtypes all end in "Event".
+func isEventType(typ string) bool {
+	return strings.HasSuffix(schemaEnum(strings.TrimSpace(typ)), "Event")
+}
+
+// jsonLDIsEvent reports whether obj's @type is an Event type.
+func jsonLDIsEvent(obj map[string]any) bool {
+	switch t := obj["@type"].(type) {
+	case string:
+		return isEventType(t)
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && isEventType(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractEvents returns the events in the page's JSON-LD, or else in its
+// microdata.
+func extractEvents(doc *goquery.Document) []Event {
+	var events []Event
+	for _, obj := range jsonLDObjects(doc) {
+		if jsonLDIsEvent(obj) {
+			events = append(events, jsonLDEvent(obj))
+		}
+	}
+	if len(events) > 0 {
+		return events
+	}
+
+	doc.Find("[itemscope][itemtype]").Each(func(i int, scope *goquery.Selection) {
+		typ, _ := scope.Attr("itemtype")
+		if !isEventType(typ) {
+			return
+		}
+		e := Event{
+			Name:           itemprop(scope, "name"),
+			Status:         schemaEnum(itemprop(scope, "eventStatus")),
+			AttendanceMode: schemaEnum(itemprop(scope, "eventAttendanceMode")),
+			URL:            itemprop(scope, "url"),
+			Price:          itemprop(scope, "price"),
+			Currency:       itemprop(scope, "priceCurrency"),
+			Source:         "microdata",
+		}
+		e.Start = parseEventTime(itemprop(scope, "startDate"))
+		e.End = parseEventTime(itemprop(scope, "endDate"))
+		if loc := scope.Find(`[itemprop~="location"]`).First(); loc.Length() > 0 {
+			if loc.Is("[itemscope]") {
+				e.Location = joinNonEmpty(itemprop(loc, "name"), strings.Join(strings.Fields(loc.Find(`[itemprop~="address"]`).First().Text()), " "))
+			} else {
+				e.Location = strings.Join(strings.Fields(itempropValue(loc)), " ")
+			}
+		}
+		setEventAmount(&e)
+		events = append(events, e)
+	})
+	return events
+}
+
+// jsonLDEvent converts an Event object.
+func jsonLDEvent(obj map[string]any) Event {
+	e := Event{
+		Name:           jsonLDString(obj, "name"),
+		Start:          parseEventTime(jsonLDString(obj, "startDate")),
+		End:            parseEventTime(jsonLDString(obj, "endDate")),
+		Location:       jsonLDLocation(obj["location"]),
+		Status:         schemaEnum(jsonLDString(obj, "eventStatus")),
+		AttendanceMode: schemaEnum(jsonLDString(obj, "eventAttendanceMode")),
+		URL:            jsonLDString(obj, "url"),
+		Source:         "json-ld",
+	}
+	if offer := jsonLDObject(obj, "offers"); offer != nil {
+		e.Price = firstNonEmpty(jsonLDString(offer, "price"), jsonLDString(offer, "lowPrice"))
+		e.Currency = jsonLDString(offer, "priceCurrency")
+	}
+	setEventAmount(&e)
+	return e
+}
+
+// jsonLDLocation formats an event location: a Place's name and address, a
+// VirtualLocation's URL, or a plain string. Lists are joined with "; ".
+func jsonLDLocation(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case []any:
+		var parts []string
+		for _, item := range t {
+			if s := jsonLDLocation(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "; ")
+	case map[string]any:
+		if jsonLDHasType(t, "VirtualLocation") {
+			return jsonLDString(t, "url")
+		}
+		address := jsonLDString(t, "address")
+		if a := jsonLDObject(t, "address"); a != nil {
+			address = joinNonEmpty(jsonLDString(a, "streetAddress"), jsonLDString(a, "addressLocality"),
+				jsonLDString(a, "addressRegion"), jsonLDString(a, "postalCode"), jsonLDString(a, "addressCountry"))
+		}
+		return joinNonEmpty(jsonLDString(t, "name"), address)
+	}
+	return ""
+}
+
+// parseEventTime parses an event date, returning nil if it is missing or
+// not in a known format.
+func parseEventTime(s string) *time.Time {
+	if t, ok := parseTimestamp(s); ok {
+		return &t
+	}
+	return nil
+}
+
+// setEventAmount parses the event's price into Amount, like a product price.
+func setEventAmount(e *Event) {
+	if e.Price == "" {
+		return
+	}
+	if amount, err := strconv.ParseFloat(strings.TrimSpace(e.Price), 64); err == nil {
+		e.Amount = &amount
+		return
+	}
+	parsed := parsePrice(e.Price)
+	e.Amount = parsed.Amount
+	if e.Currency == "" {
+		e.Currency = parsed.Currency
+	}
+}
+
+// joinNonEmpty joins the non-empty values with ", ".
+func joinNonEmpty(values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// String formats the event for plain-text output.
+func (e Event) String() string {
+	parts := []string{firstNonEmpty(e.Name, "(unnamed)")}
+	if e.Start != nil {
+		when := e.Start.Format(time.RFC3339)
+		if e.End != nil {
+			when += " to " + e.End.Format(time.RFC3339)
+		}
+		parts = append(parts, when)
+	}
+	if e.Location != "" {
+		parts = append(parts, "at "+e.Location)
+	}
+	if e.Price != "" {
+		parts = append(parts, strings.TrimSpace(e.Price+" "+e.Currency))
+	}
+	if e.Status != "" && e.Status != "EventScheduled" {
+		parts = append(parts, e.Status)
+	}
+	return strings.Join(parts, " - ")
+}
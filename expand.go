@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// expandCache maps original link URLs to their resolved destinations for
+// the duration of the run.
+var expandCache = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// resolveRedirects follows the redirects of u and returns the final URL. A
+// HEAD request is tried first, falling back to GET for servers that reject
+// HEAD.
+func resolveRedirects(ctx context.Context, u string) (string, error) {
+	resp, err := requestURL(ctx, http.MethodHead, u)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = requestURL(ctx, http.MethodGet, u)
+	}
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
+
+// requestURL sends a bodyless request for u with the shared client.
+func requestURL(ctx context.Context, method, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgents != nil {
+		req.Header.Set("User-Agent", userAgents.pick())
+	}
+	return doRequest(req)
+}
+
+// expandLinks sets Resolved on every link whose final destination differs
+// from its URL.
+func expandLinks(ctx context.Context, links []Link) {
+	for i := range links {
+		u := links[i].URL
+		expandCache.Lock()
+		final, ok := expandCache.m[u]
+		expandCache.Unlock()
+		if !ok {
+			var err error
+			if final, err = resolveRedirects(ctx, u); err != nil {
+				final = ""
+			}
+			expandCache.Lock()
+			expandCache.m[u] = final
+			expandCache.Unlock()
+		}
+		if final != "" && final != u {
+			links[i].Resolved = final
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PageError is a URL that could not be scraped, with the HTTP status when
+// the server answered with one.
+type PageError struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error"`
+}
+
+// newPageError describes the failure of url.
+func newPageError(url string, err error) PageError {
+	pe := PageError{URL: url, Error: err.Error()}
+	var se *statusError
+	if errors.As(err, &se) {
+		pe.Status = se.Code
+	}
+	return pe
+}
+
+// String formats the failure for plain-text output.
+func (e PageError) String() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("%s [%d] %s", e.URL, e.Status, e.Error)
+	}
+	return e.URL + " " + e.Error
+}
+
+// writeErrors writes the -only-errors report.
+func writeErrors(w io.Writer, errs []PageError) {
+	if len(errs) == 0 {
+		fmt.Fprintln(w, "No errors")
+		return
+	}
+	fmt.Fprintf(w, "%d URLs failed:\n", len(errs))
+	for i, e := range errs {
+		fmt.Fprintf(w, "%d. %s\n", i+1, e)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// QA is a question and its answer from an FAQ.
+type QA struct {
+	Question   string `json:"question"`
+	Answer     string `json:"answer"`
+	AnswerHTML string `json:"answer_html,omitempty"`
+	Source     string `json:"source"` // json-ld, dl or details
+}
+
+// extractFAQ returns the questions of a schema.org FAQPage in JSON-LD. If
+// the page has none it falls back to <dt>/<dd> pairs and <details> elements
+// with a <summary>.
+func extractFAQ(doc *goquery.Document) []QA {
+	var faq []QA
+	for _, obj := range jsonLDObjects(doc) {
+		if !jsonLDHasType(obj, "FAQPage") {
+			continue
+		}
+		for _, q := range jsonLDObjectList(obj, "mainEntity") {
+			if !jsonLDHasType(q, "Question") {
+				continue
+			}
+			qa := QA{Question: strings.TrimSpace(jsonLDString(q, "name")), Source: "json-ld"}
+			if answer := jsonLDObject(q, "acceptedAnswer"); answer != nil {
+				qa.AnswerHTML = strings.TrimSpace(jsonLDString(answer, "text"))
+				qa.Answer = htmlText(qa.AnswerHTML)
+			}
+			if qa.Question != "" {
+				faq = append(faq, qa)
+			}
+		}
+	}
+	if len(faq) > 0 {
+		return faq
+	}
+
+	doc.Find("dt").Each(func(i int, dt *goquery.Selection) {
+		dd := dt.NextUntil("dt").Filter("dd")
+		if dd.Length() == 0 {
+			return
+		}
+		var texts, html []string
+		dd.Each(func(i int, s *goquery.Selection) {
+			texts = append(texts, s.Text())
+			if h, err := s.Html(); err == nil {
+				html = append(html, strings.TrimSpace(h))
+			}
+		})
+		faq = appendQA(faq, dt.Text(), strings.Join(texts, " "), strings.Join(html, "\n"), "dl")
+	})
+	doc.Find("details").Each(func(i int, s *goquery.Selection) {
+		summary := s.ChildrenFiltered("summary").First()
+		if summary.Length() == 0 {
+			return
+		}
+		body := s.Clone()
+		body.ChildrenFiltered("summary").First().Remove()
+		html, _ := body.Html()
+		faq = appendQA(faq, summary.Text(), body.Text(), strings.TrimSpace(html), "details")
+	})
+	return faq
+}
+
+// appendQA adds a question found by the fallback heuristics if both its
+// question and answer have text.
+func appendQA(faq []QA, question, answer, html, source string) []QA {
+	question = strings.Join(strings.Fields(question), " ")
+	answer = strings.Join(strings.Fields(answer), " ")
+	if question == "" || answer == "" {
+		return faq
+	}
+	return append(faq, QA{Question: question, Answer: answer, AnswerHTML: html, Source: source})
+}
+
+// htmlText returns the text of an HTML fragment with whitespace collapsed.
+func htmlText(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+// String formats the question for plain-text output.
+func (qa QA) String() string {
+	return "Q: " + qa.Question + "\n   A: " + qa.Answer
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// client is the HTTP client used for all requests.
+var client = &http.Client{}
+
+// connSlots bounds the number of requests in flight across all hosts when
+// -max-connections is set. A slot is held until the response body is closed.
+var connSlots chan struct{}
+
+// slotBody releases its connection slot when the body is closed.
+type slotBody struct {
+	io.ReadCloser
+	once sync.Once
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { <-connSlots })
+	return err
+}
+
+// doRequest sends req with the shared client, waiting for a free connection
+// slot first if the number of connections is limited. It is signed first
+// with -sign, and with -har it is answered from the HAR file instead.
+func doRequest(req *http.Request) (*http.Response, error) {
+	if signer != nil {
+		if err := signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	if requestLog != nil {
+		requestLog.log(req)
+	}
+	if harResponses != nil {
+		return harResponses.response(req)
+	}
+	if connSlots == nil {
+		return client.Do(req)
+	}
+	select {
+	case connSlots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		<-connSlots
+		return nil, err
+	}
+	resp.Body = &slotBody{ReadCloser: resp.Body}
+	return resp, nil
+}
+
+// setupClient configures the shared client's transport from opts.
+func setupClient(opts Options) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = newDNSCache(opts.Resolve).dialContext
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return fmt.Errorf("-client-cert and -client-key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return fmt.Errorf("error loading client certificate: %v", err)
+		}
+		transport.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	client.Transport = transport
+	if opts.MaxConnections > 0 {
+		connSlots = make(chan struct{}, opts.MaxConnections)
+	}
+	var err error
+	if userAgents, err = newUARotator(opts); err != nil {
+		return err
+	}
+	if bearerTokens, err = newTokenPool(opts.TokensFile); err != nil {
+		return err
+	}
+	if signer, err = newSigner(opts); err != nil {
+		return err
+	}
+	totalRetries = newRetryBudget(opts.MaxTotalRetries)
+	if opts.HAR != "" {
+		if harResponses, err = loadHAR(opts.HAR); err != nil {
+			return err
+		}
+	}
+	requestLog, err = newRequestLogger(opts.LogRequests, opts.LogSecrets)
+	return err
+}
+
+// newRequest builds the HTTP request for url from the method and body
+// options.
+func newRequest(ctx context.Context, url string, opts Options) (*http.Request, error) {
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch {
+	case opts.Data != "" && opts.JSONBody != "":
+		return nil, fmt.Errorf("-data and -json-body cannot be used together")
+	case opts.Data != "":
+		body = strings.NewReader(opts.Data)
+		contentType = "application/x-www-form-urlencoded"
+	case opts.JSONBody != "":
+		body = strings.NewReader(opts.JSONBody)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if userAgents != nil {
+		req.Header.Set("User-Agent", userAgents.pick())
+	}
+	// An explicit Authorization header takes precedence over the token
+	if bearerTokens != nil {
+		req.Header.Set("Authorization", "Bearer "+bearerTokens.pick())
+	}
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// followOptions returns opts for fetching a URL found on a page, such as a
+// crawled link or an iframe: -method, -data and -json-body only apply to the
+// URL that was asked for, so it is fetched with a plain GET.
+func followOptions(opts Options) Options {
+	opts.Method, opts.Data, opts.JSONBody = "", "", ""
+	return opts
+}
+
+// parseHeader parses a header given as "Name: value".
+func parseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q, want \"Name: value\"", s)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// fetchPage sends the request for url and returns the response.
+func fetchPage(ctx context.Context, url string, opts Options) (*http.Response, error) {
+	req, err := newRequest(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.UseCache && !opts.refetch {
+		if resp, ok := loadCached(opts.CacheDir, req, opts.CacheTTL); ok {
+			return resp, nil
+		}
+	}
+	delay := hostDelay(req.URL.Host, opts)
+	if err := throttle.wait(ctx, req.URL.Host, delay); err != nil {
+		return nil, fmt.Errorf("error fetching URL: %v", err)
+	}
+	resp, err := doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching URL: %v", err)
+	}
+	if opts.UseCache && resp.StatusCode == http.StatusOK {
+		if err := storeCached(opts.CacheDir, req, resp, opts.RetryBodyMatch); err != nil {
+			log.Printf("%s: %v", url, err)
+		}
+	}
+	throttle.record(req.URL.Host, delay, resp)
+	if bearerTokens != nil {
+		bearerTokens.record(req, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// fieldRename is one entry of -field-map: a JSON output key and the name it
+// is written as.
+type fieldRename struct {
+	From, To string
+}
+
+// fieldMap controls the keys of -format json output. Mapped keys come first,
+// in the order given, followed by the others in their usual order.
+type fieldMap []fieldRename
+
+// outputFields is the -field-map applied to JSON output; empty leaves the
+// output as is.
+var outputFields fieldMap
+
+// scrapeDataKeys returns the JSON keys of ScrapeData in field order.
+func scrapeDataKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(ScrapeData{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// parseFieldMap parses a comma-separated list of key->name renames. A key
+// without "->" keeps its name but is moved to that position.
+func parseFieldMap(s string) (fieldMap, error) {
+	known := make(map[string]bool)
+	for _, k := range scrapeDataKeys() {
+		known[k] = true
+	}
+	var m fieldMap
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(entry, "->")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok {
+			to = from
+		}
+		if !known[from] || to == "" {
+			return nil, fmt.Errorf("invalid -field-map entry %q, want key->name with one of: %s", entry, strings.Join(scrapeDataKeys(), ", "))
+		}
+		if seen[from] {
+			return nil, fmt.Errorf("-field-map lists %q twice", from)
+		}
+		seen[from] = true
+		m = append(m, fieldRename{From: from, To: to})
+	}
+	return m, nil
+}
+
+// writeMappedJSON writes data as indented JSON with the keys renamed and
+// ordered by m.
+func writeMappedJSON(w io.Writer, data ScrapeData, m fieldMap) error {
+	var raw bytes.Buffer
+	enc := json.NewEncoder(&raw)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw.Bytes(), &values); err != nil {
+		return err
+	}
+
+	var keys, names []string
+	mapped := make(map[string]bool)
+	for _, r := range m {
+		keys, names = append(keys, r.From), append(names, r.To)
+		mapped[r.From] = true
+	}
+	for _, k := range scrapeDataKeys() {
+		if !mapped[k] {
+			keys, names = append(keys, k), append(names, k)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	first := true
+	for i, k := range keys {
+		value, ok := values[k]
+		if !ok {
+			continue
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(names[i])
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(w)
+	return err
+}
@@ -2,9 +2,19 @@ module gop
 
 go 1.24
 
-require github.com/PuerkitoBio/goquery v1.10.3
+require (
+	github.com/PuerkitoBio/goquery v1.10.3
+	github.com/atotto/clipboard v0.1.4
+	github.com/parquet-go/parquet-go v0.25.1
+	golang.org/x/net v0.39.0
+	golang.org/x/text v0.24.0
+)
 
 require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/andybalholm/cascadia v1.3.3 // indirect
-	golang.org/x/net v0.39.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/sys v0.32.0 // indirect
 )
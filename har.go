@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// harFile is the part of a HAR (HTTP Archive) file needed to replay its
+// responses.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status      int         `json:"status"`
+		StatusText  string      `json:"statusText"`
+		Headers     []harHeader `json:"headers"`
+		RedirectURL string      `json:"redirectURL"`
+		Content     struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// maxHARRedirects bounds the redirect chains followed within a HAR file.
+const maxHARRedirects = 10
+
+// harArchive holds the responses of a HAR file by method and URL.
+type harArchive struct {
+	entries   map[string]*harEntry
+	documents []string // URLs of the HTML responses, in capture order
+}
+
+// harResponses is the archive requests are answered from with -har; nil
+// fetches from the network.
+var harResponses *harArchive
+
+// loadHAR reads a HAR file. When a URL was captured more than once the last
+// response, the one the browser ended up with, is kept.
+func loadHAR(path string) (*harArchive, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading HAR file: %v", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(content, &har); err != nil {
+		return nil, fmt.Errorf("error parsing HAR file: %v", err)
+	}
+	archive := &harArchive{entries: make(map[string]*harEntry)}
+	for i := range har.Log.Entries {
+		e := &har.Log.Entries[i]
+		key := harKey(e.Request.Method, e.Request.URL)
+		if _, seen := archive.entries[key]; !seen && e.Response.Status == http.StatusOK && isHTMLType(e.Response.Content.MimeType) {
+			archive.documents = append(archive.documents, e.Request.URL)
+		}
+		archive.entries[key] = e
+	}
+	return archive, nil
+}
+
+// harKey is the lookup key of a request, without its fragment.
+func harKey(method, rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.Fragment = ""
+		rawURL = u.String()
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	return method + " " + rawURL
+}
+
+// isHTMLType reports whether a MIME type is an HTML document.
+func isHTMLType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// response answers req from the archive, following recorded redirects like
+// the HTTP client would. Requests that were not captured fail rather than
+// going to the network.
+func (a *harArchive) response(req *http.Request) (*http.Response, error) {
+	for hops := 0; hops <= maxHARRedirects; hops++ {
+		e, ok := a.entries[harKey(req.Method, req.URL.String())]
+		if !ok {
+			return nil, fmt.Errorf("%s %s is not in the HAR file", req.Method, req.URL)
+		}
+		resp := e.httpResponse(req)
+		location := e.Response.RedirectURL
+		if location == "" {
+			location = resp.Header.Get("Location")
+		}
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return resp, nil
+		}
+		next, err := req.URL.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("error following redirect: %v", err)
+		}
+		req = req.Clone(req.Context())
+		req.URL = next
+		if resp.StatusCode != http.StatusTemporaryRedirect && resp.StatusCode != http.StatusPermanentRedirect {
+			req.Method = http.MethodGet
+		}
+	}
+	return nil, fmt.Errorf("stopped after %d redirects", maxHARRedirects)
+}
+
+// httpResponse converts the captured response to an *http.Response.
+func (e *harEntry) httpResponse(req *http.Request) *http.Response {
+	body := []byte(e.Response.Content.Text)
+	if e.Response.Content.Encoding == "base64" {
+		if decoded, err := base64.StdEncoding.DecodeString(e.Response.Content.Text); err == nil {
+			body = decoded
+		}
+	}
+	header := make(http.Header)
+	for _, h := range e.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	// The body in a HAR file is already decoded
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	if header.Get("Content-Type") == "" && e.Response.Content.MimeType != "" {
+		header.Set("Content-Type", e.Response.Content.MimeType)
+	}
+	status := e.Response.StatusText
+	if status == "" {
+		status = http.StatusText(e.Response.Status)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(e.Response.Status) + " " + status,
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// scrapeHAR scrapes every HTML document in the archive and merges the
+// results. Pages that fail are logged and skipped.
+func scrapeHAR(ctx context.Context, opts Options) (ScrapeData, error) {
+	var all ScrapeData
+	for _, u := range harResponses.documents {
+		data, err := scrapePage(ctx, u, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return all, errInterrupted
+			}
+			log.Printf("%s: %v", u, err)
+			continue
+		}
+		all = mergeData(all, data)
+	}
+	if len(harResponses.documents) == 0 {
+		return all, fmt.Errorf("HAR file has no HTML responses")
+	}
+	return all, nil
+}
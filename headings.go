@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Heading is an <h1>-<h6> element and the fragment that links to it. When
+// the heading has no id, ID is a slug of its text and Generated is set.
+type Heading struct {
+	Level     int    `json:"level"`
+	Text      string `json:"text"`
+	ID        string `json:"id,omitempty"`
+	Generated bool   `json:"generated,omitempty"`
+	Anchor    string `json:"anchor,omitempty"`
+}
+
+// extractHeadings collects the page headings in document order with their
+// anchors, resolved against page.
+func extractHeadings(doc *goquery.Document, page *url.URL) []Heading {
+	// Generated slugs must not clash with ids already on the page
+	used := make(map[string]bool)
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id, _ := s.Attr("id")
+		used[id] = true
+	})
+
+	var headings []Heading
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		h := Heading{
+			Level: int(goquery.NodeName(s)[1] - '0'),
+			Text:  strings.Join(strings.Fields(s.Text()), " "),
+		}
+		if h.Text == "" {
+			return
+		}
+		if id, ok := s.Attr("id"); ok && strings.TrimSpace(id) != "" {
+			h.ID = id
+		} else if slug := slugify(h.Text); slug != "" {
+			h.ID = slug
+			for n := 1; used[h.ID]; n++ {
+				h.ID = fmt.Sprintf("%s-%d", slug, n)
+			}
+			used[h.ID] = true
+			h.Generated = true
+		}
+		if h.ID != "" && page != nil {
+			u := *page
+			u.Fragment = h.ID
+			h.Anchor = u.String()
+		}
+		headings = append(headings, h)
+	})
+	return headings
+}
+
+// slugify turns heading text into a fragment id: lower-case letters and
+// digits, with runs of anything else collapsed to a single hyphen.
+func slugify(text string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// String formats the heading for plain-text output, indented by level.
+func (h Heading) String() string {
+	s := strings.Repeat("  ", h.Level-1) + h.Text
+	if h.ID != "" {
+		s += " #" + h.ID
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// resourceHintRels are the <link rel> values collected by
+// extractResourceHints.
+var resourceHintRels = []string{"preconnect", "preload", "prefetch", "dns-prefetch", "modulepreload"}
+
+// ResourceHint is a <link> resource hint declared by the page.
+type ResourceHint struct {
+	Rel  string `json:"rel"`
+	Href string `json:"href"`
+	As   string `json:"as,omitempty"`
+}
+
+// extractResourceHints collects the preconnect, preload, prefetch and
+// dns-prefetch hints with their absolute href.
+func extractResourceHints(doc *goquery.Document, base *url.URL) []ResourceHint {
+	var hints []ResourceHint
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		href, _ := s.Attr("href")
+		as, _ := s.Attr("as")
+		abs, ok := resolveURL(base, href)
+		if !ok {
+			return
+		}
+		for _, r := range parseRel(rel) {
+			for _, hint := range resourceHintRels {
+				if r == hint {
+					hints = append(hints, ResourceHint{Rel: r, Href: abs, As: strings.TrimSpace(as)})
+				}
+			}
+		}
+	})
+	return hints
+}
+
+// String formats the hint for plain-text output.
+func (h ResourceHint) String() string {
+	s := h.Rel + " " + h.Href
+	if h.As != "" {
+		s += " (as " + h.As + ")"
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractIFrames returns the absolute src of every iframe, skipping
+// about:blank and data: URIs.
+func extractIFrames(doc *goquery.Document, base *url.URL) []string {
+	var frames []string
+	doc.Find("iframe[src]").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		lower := strings.ToLower(strings.TrimSpace(src))
+		if lower == "" || strings.HasPrefix(lower, "about:") || strings.HasPrefix(lower, "data:") || strings.HasPrefix(lower, "javascript:") {
+			return
+		}
+		if abs, ok := resolveURL(base, src); ok {
+			frames = append(frames, abs)
+		}
+	})
+	return frames
+}
+
+// sameOrigin reports whether u has the same scheme and host as origin.
+func sameOrigin(origin *url.URL, u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == origin.Scheme && parsed.Host == origin.Host
+}
+
+// scrapeIFrames scrapes the same-origin iframes listed in data with the
+// same options, including -select rules and -exclude-selector, and merges
+// their data into it. Items found in them go to out when it is non-nil.
+// Iframes inside iframes are not followed.
+func scrapeIFrames(ctx context.Context, data ScrapeData, pageURL *url.URL, opts Options, out itemWriter) ScrapeData {
+	frameOpts := followOptions(opts)
+	frameOpts.FollowIFrames = false
+	for _, src := range data.IFrames {
+		if !sameOrigin(pageURL, src) {
+			continue
+		}
+		frame, err := scrapePageTo(ctx, src, frameOpts, out)
+		if err != nil {
+			log.Printf("Failed to scrape iframe %s: %v", src, err)
+			continue
+		}
+		frame.IFrames = nil
+		// Its time is already counted in the page's iframes stage
+		frame.Profile = nil
+		data = mergeData(data, frame)
+	}
+	return data
+}
+
+// parseSrcdocFrames parses the inline documents of iframes with a srcdoc
+// attribute like the page itself and merges their data into data. Their
+// relative URLs resolve against the page, as in browsers.
+func parseSrcdocFrames(doc *goquery.Document, page *url.URL, data ScrapeData, opts Options, out itemWriter) (ScrapeData, error) {
+	frameOpts := opts
+	frameOpts.FollowIFrames = false
+	var err error
+	doc.Find("iframe[srcdoc]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		srcdoc, _ := s.Attr("srcdoc")
+		var frame ScrapeData
+		frame, err = parsePageTo(strings.NewReader(srcdoc), "text/html; charset=utf-8", page, frameOpts, out)
+		if err != nil {
+			return false
+		}
+		data = mergeData(data, frame)
+		return true
+	})
+	return data, err
+}
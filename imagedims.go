@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// imageHeaderBytes is how much of each image is requested to read its
+// dimensions. JPEG headers can follow large EXIF blocks, so this is generous.
+const imageHeaderBytes = 64 * 1024
+
+// imageProbeConcurrency is how many images prefetchImages probes at once.
+const imageProbeConcurrency = 4
+
+// Dimensions is the pixel size of an image.
+type Dimensions struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// imageInfo is what one ranged request for an image reveals: its
+// dimensions, and its total size, or -1 if the server did not say.
+type imageInfo struct {
+	Dims *Dimensions
+	Size int64
+}
+
+// imageProbe is the cached result of probing one image. The sync.Once lets
+// concurrent callers wait for a request already in flight.
+type imageProbe struct {
+	once sync.Once
+	info imageInfo
+}
+
+// imageCache holds the probe of each image URL during the run, shared by
+// -image-dims and -page-weight.
+var imageCache = struct {
+	sync.Mutex
+	m map[string]*imageProbe
+}{m: make(map[string]*imageProbe)}
+
+// fetchImageInfo requests the start of the image at u, decodes its PNG,
+// JPEG or GIF header, and reads its size from the Content-Range or
+// Content-Length header.
+func fetchImageInfo(ctx context.Context, u string) (imageInfo, error) {
+	info := imageInfo{Size: -1}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return info, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", imageHeaderBytes-1))
+	if userAgents != nil {
+		req.Header.Set("User-Agent", userAgents.pick())
+	}
+	resp, err := doRequest(req)
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+				info.Size = n
+			}
+		}
+	case http.StatusOK:
+		info.Size = resp.ContentLength
+	default:
+		return info, &statusError{Code: resp.StatusCode}
+	}
+
+	// Servers that ignore Range send the whole image; read only the head
+	head, err := io.ReadAll(io.LimitReader(resp.Body, imageHeaderBytes))
+	if err != nil {
+		return info, err
+	}
+	if info.Size < 0 && resp.StatusCode == http.StatusOK && len(head) < imageHeaderBytes {
+		info.Size = int64(len(head))
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+		info.Dims = &Dimensions{Width: cfg.Width, Height: cfg.Height}
+	}
+	return info, nil
+}
+
+// probeImage returns the cached probe of the image at the absolute URL abs,
+// fetching it on first use.
+func probeImage(ctx context.Context, abs string) imageInfo {
+	imageCache.Lock()
+	p, ok := imageCache.m[abs]
+	if !ok {
+		p = &imageProbe{}
+		imageCache.m[abs] = p
+	}
+	imageCache.Unlock()
+	p.once.Do(func() {
+		info, err := fetchImageInfo(ctx, abs)
+		if err != nil {
+			info = imageInfo{Size: -1}
+		}
+		p.info = info
+	})
+	return p.info
+}
+
+// prefetchImages probes the images in srcs a few at a time so that
+// imageDimensions and pageWeight then read them from the cache.
+func prefetchImages(ctx context.Context, srcs []string, base *url.URL) {
+	sem := make(chan struct{}, imageProbeConcurrency)
+	var wg sync.WaitGroup
+	for _, src := range srcs {
+		abs, ok := resolveURL(base, src)
+		if !ok || !strings.HasPrefix(abs, "http") {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			probeImage(ctx, abs)
+		}()
+	}
+	wg.Wait()
+}
+
+// imageDimensions returns the dimensions of each image in srcs that could
+// be determined, keyed by src as it appears on the page.
+func imageDimensions(ctx context.Context, srcs []string, base *url.URL) map[string]Dimensions {
+	result := make(map[string]Dimensions)
+	for _, src := range srcs {
+		abs, ok := resolveURL(base, src)
+		if !ok {
+			continue
+		}
+		if dims := probeImage(ctx, abs).Dims; dims != nil {
+			result[src] = *dims
+		}
+	}
+	return result
+}
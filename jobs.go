@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// job is one line of a -jobs-file: a URL and the settings to scrape it with.
+type job struct {
+	URL       string            `json:"url"`
+	Selectors map[string]string `json:"selectors"` // Name to selector[@attr], like -select
+	Headers   map[string]string `json:"headers"`
+	Depth     *int              `json:"depth"`
+}
+
+// jobResult is the outcome of a single job.
+type jobResult struct {
+	URL    string
+	Data   ScrapeData
+	Error  string
+	Status int // HTTP status of a failed job, if the server answered
+}
+
+// loadJobs reads a JSON-lines file of jobs. Blank lines are ignored.
+func loadJobs(path string) ([]job, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening jobs file: %v", err)
+	}
+	defer file.Close()
+
+	var jobs []job
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var j job
+		if err := json.Unmarshal([]byte(text), &j); err != nil {
+			return nil, fmt.Errorf("jobs file line %d: %v", line, err)
+		}
+		if j.URL == "" {
+			return nil, fmt.Errorf("jobs file line %d: missing url", line)
+		}
+		jobs = append(jobs, j)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading jobs file: %v", err)
+	}
+	return jobs, nil
+}
+
+// jobOptions returns opts with the job's selectors, headers and depth
+// applied on top.
+func jobOptions(j job, opts Options) (Options, error) {
+	if len(j.Selectors) > 0 {
+		opts.Selects = nil
+		for _, name := range sortedKeys(j.Selectors) {
+			rule, err := parseSelectRule(name + "=" + j.Selectors[name])
+			if err != nil {
+				return opts, err
+			}
+			opts.Selects = append(opts.Selects, rule)
+		}
+	}
+	if len(j.Headers) > 0 {
+		headers := make(map[string]string)
+		for k, v := range opts.Headers {
+			headers[k] = v
+		}
+		for k, v := range j.Headers {
+			headers[k] = v
+		}
+		opts.Headers = headers
+	}
+	if j.Depth != nil {
+		opts.Depth = *j.Depth
+	}
+	// Jobs never share a crawl state file
+	opts.StateFile, opts.Resume = "", false
+	return opts, nil
+}
+
+// runJobs runs every job in the file at path in order. A failing job is
+// recorded in its result and does not stop the others.
+func runJobs(ctx context.Context, path string, opts Options) ([]jobResult, error) {
+	jobs, err := loadJobs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []jobResult
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		result := jobResult{URL: j.URL}
+		jobOpts, err := jobOptions(j, opts)
+		if err == nil {
+			if jobOpts.Depth > 0 {
+				result.Data, err = crawl(ctx, j.URL, jobOpts)
+			} else {
+				result.Data, err = scrapePage(ctx, j.URL, jobOpts)
+			}
+		}
+		if err != nil {
+			log.Printf("Job %s failed: %v", j.URL, err)
+			pe := newPageError(j.URL, err)
+			result.Error, result.Status = pe.Error, pe.Status
+		}
+		result.Data.ContentHash = contentHash(result.Data)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// saveJobResults writes the results of a -jobs-file run to filename.
+func saveJobResults(results []jobResult, filename, format string) error {
+	if format != "json" && format != "text" && format != "es-bulk" {
+		return fmt.Errorf("-format %s is not supported with -jobs-file", format)
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := writeJobResults(writer, results, format); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	return writer.Flush()
+}
+
+// writeJobResults writes the results of a -jobs-file run. JSON output is an
+// array in job order, each entry naming its job's URL, so the same URL can
+// appear in several jobs; es-bulk output has a document per successful job,
+// and text output has a section per job.
+func writeJobResults(w io.Writer, results []jobResult, format string) error {
+	if format == "es-bulk" {
+		for _, r := range results {
+			if r.Error != "" {
+				continue
+			}
+			if err := writeESBulk(w, r.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if format == "json" {
+		type entry struct {
+			Job string `json:"job"`
+			ScrapeData
+			Error string `json:"error,omitempty"`
+		}
+		entries := make([]entry, len(results))
+		for i, r := range results {
+			entries[i] = entry{r.URL, r.Data, r.Error}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(entries)
+	}
+
+	for i, r := range results {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "=== %s ===\n", r.URL)
+		if r.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n", r.Error)
+			continue
+		}
+		writeText(w, r.Data)
+	}
+	return nil
+}
+
+// jobFailures returns the failed jobs and the failed pages of crawl jobs.
+func jobFailures(results []jobResult) []PageError {
+	var failures []PageError
+	for _, r := range results {
+		if r.Error != "" {
+			failures = append(failures, PageError{URL: r.URL, Status: r.Status, Error: r.Error})
+		}
+		failures = append(failures, r.Data.Errors...)
+	}
+	return failures
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// jsonLDObjects returns every JSON-LD object embedded in the document.
+// Top-level arrays and @graph lists are flattened; scripts that fail to
+// decode are skipped.
+func jsonLDObjects(doc *goquery.Document) []map[string]any {
+	var objects []map[string]any
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+			return
+		}
+		objects = appendJSONLD(objects, v)
+	})
+	return objects
+}
+
+// appendJSONLD appends the objects found in v to objects.
+func appendJSONLD(objects []map[string]any, v any) []map[string]any {
+	switch t := v.(type) {
+	case []any:
+		for _, item := range t {
+			objects = appendJSONLD(objects, item)
+		}
+	case map[string]any:
+		objects = append(objects, t)
+		if graph, ok := t["@graph"]; ok {
+			objects = appendJSONLD(objects, graph)
+		}
+	}
+	return objects
+}
+
+// jsonLDString returns the value of key in obj as a string. Numbers are
+// formatted, and for lists or nested objects the first value or the
+// object's @value, name or @id is used.
+func jsonLDString(obj map[string]any, key string) string {
+	return jsonLDValue(obj[key])
+}
+
+// jsonLDValue converts a decoded JSON-LD value to a string.
+func jsonLDValue(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case []any:
+		if len(t) > 0 {
+			return jsonLDValue(t[0])
+		}
+	case map[string]any:
+		for _, key := range []string{"@value", "name", "@id", "url"} {
+			if s := jsonLDValue(t[key]); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDObject returns the object stored under key in obj. If the value is
+// a list, its first object is returned.
+func jsonLDObject(obj map[string]any, key string) map[string]any {
+	switch t := obj[key].(type) {
+	case map[string]any:
+		return t
+	case []any:
+		for _, item := range t {
+			if m, ok := item.(map[string]any); ok {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// jsonLDObjectList returns every object stored under key in obj, whether it
+// holds a single object or a list.
+func jsonLDObjectList(obj map[string]any, key string) []map[string]any {
+	switch t := obj[key].(type) {
+	case map[string]any:
+		return []map[string]any{t}
+	case []any:
+		var objects []map[string]any
+		for _, item := range t {
+			if m, ok := item.(map[string]any); ok {
+				objects = append(objects, m)
+			}
+		}
+		return objects
+	}
+	return nil
+}
+
+// jsonLDHasType reports whether obj's @type is, or includes, typ.
+func jsonLDHasType(obj map[string]any, typ string) bool {
+	switch t := obj["@type"].(type) {
+	case string:
+		return t == typ || t == "http://schema.org/"+typ || t == "https://schema.org/"+typ
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && jsonLDHasType(map[string]any{"@type": s}, typ) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findJSONLD returns the first JSON-LD object in doc of the given type.
+func findJSONLD(doc *goquery.Document, typ string) map[string]any {
+	for _, obj := range jsonLDObjects(doc) {
+		if jsonLDHasType(obj, typ) {
+			return obj
+		}
+	}
+	return nil
+}
+
+// schemaEnum strips the schema.org prefix from enumeration values such as
+// "https://schema.org/InStock".
+func schemaEnum(s string) string {
+	s = strings.TrimPrefix(s, "https://schema.org/")
+	return strings.TrimPrefix(s, "http://schema.org/")
+}
+
+// metaContent returns the content of the first <meta> tag whose property or
+// name attribute is key.
+func metaContent(doc *goquery.Document, key string) string {
+	content, _ := doc.Find(`meta[property="` + key + `"], meta[name="` + key + `"]`).First().Attr("content")
+	return strings.TrimSpace(content)
+}
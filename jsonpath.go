@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// evalJSONPath returns the values in v matched by a dot-separated path in the
+// style of gjson: object keys, array indexes, and "#" or "*" to match every
+// element of an array or object, e.g. "items.#.name".
+func evalJSONPath(v any, path string) []any {
+	current := []any{v}
+	if path = strings.TrimSpace(path); path == "" {
+		return current
+	}
+	for _, seg := range strings.Split(path, ".") {
+		var next []any
+		for _, c := range current {
+			switch node := c.(type) {
+			case map[string]any:
+				if seg == "#" || seg == "*" {
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, node[k])
+					}
+				} else if child, ok := node[seg]; ok {
+					next = append(next, child)
+				}
+			case []any:
+				if seg == "#" || seg == "*" {
+					next = append(next, node...)
+				} else if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(node) {
+					next = append(next, node[i])
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// jsonValueString formats a matched value: strings as they are, anything
+// else as compact JSON.
+func jsonValueString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// parseJSONPaths decodes the JSON document read from r and collects the
+// values matched by each path, keyed by the path.
+func parseJSONPaths(r io.Reader, paths []string) (map[string][]string, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	result := make(map[string][]string)
+	for _, path := range paths {
+		for _, v := range evalJSONPath(doc, path) {
+			result[path] = append(result[path], jsonValueString(v))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Link is a hyperlink extracted from the page.
+type Link struct {
+	URL      string   `json:"url"`
+	Rel      []string `json:"rel,omitempty"`       // Lower-cased rel values, e.g. nofollow, sponsored, ugc
+	Resolved string   `json:"resolved,omitempty"`  // Final destination after redirects, with -expand-urls
+	Context  string   `json:"context,omitempty"`   // Text around the link, with -link-context
+	ImageMap *MapArea `json:"image_map,omitempty"` // Set for <area> links of an image map
+	Sources  []string `json:"sources,omitempty"`   // Pages the link was found on, with -merge
+}
+
+// MapArea describes the image-map region an <area> link covers.
+type MapArea struct {
+	Alt    string `json:"alt,omitempty"`
+	Shape  string `json:"shape,omitempty"`
+	Coords string `json:"coords,omitempty"`
+}
+
+// documentBase returns the URL relative links in doc are resolved against:
+// the <base href> if the document sets one, otherwise the page URL.
+func documentBase(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	href, ok := doc.Find("base[href]").First().Attr("href")
+	if !ok {
+		return pageURL
+	}
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return pageURL
+	}
+	if pageURL != nil {
+		u = pageURL.ResolveReference(u)
+	}
+	return u
+}
+
+// resolveURL resolves ref against base. It returns false for empty or
+// unparsable references. If base is nil, ref is returned as is.
+func resolveURL(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	return u.String(), true
+}
+
+// extractAlternates maps each <link rel="alternate" hreflang> code,
+// including x-default, to its absolute URL.
+func extractAlternates(doc *goquery.Document, base *url.URL) map[string]string {
+	alternates := make(map[string]string)
+	doc.Find("link[hreflang][href]").Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !(Link{Rel: parseRel(rel)}).hasRel("alternate") {
+			return
+		}
+		lang, _ := s.Attr("hreflang")
+		href, _ := s.Attr("href")
+		lang = strings.TrimSpace(lang)
+		if abs, ok := resolveURL(base, href); ok && lang != "" {
+			if _, dup := alternates[lang]; !dup {
+				alternates[lang] = abs
+			}
+		}
+	})
+	if len(alternates) == 0 {
+		return nil
+	}
+	return alternates
+}
+
+// extractMetaRefresh returns the absolute target of a
+// <meta http-equiv="refresh" content="N; url=..."> tag, if the page has one.
+func extractMetaRefresh(doc *goquery.Document, base *url.URL) string {
+	var target string
+	doc.Find("meta[http-equiv][content]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		equiv, _ := s.Attr("http-equiv")
+		if !strings.EqualFold(strings.TrimSpace(equiv), "refresh") {
+			return true
+		}
+		content, _ := s.Attr("content")
+		if ref := parseRefresh(content); ref != "" {
+			target, _ = resolveURL(base, ref)
+			return false
+		}
+		return true
+	})
+	return target
+}
+
+// parseRefresh returns the URL part of a refresh content value such as
+// "0; url='/next'", or "" if it only reloads the page.
+func parseRefresh(content string) string {
+	i := strings.IndexAny(content, ";,")
+	if i < 0 {
+		return ""
+	}
+	ref := strings.TrimSpace(content[i+1:])
+	if len(ref) >= 4 && strings.EqualFold(ref[:3], "url") {
+		if rest := strings.TrimSpace(ref[3:]); strings.HasPrefix(rest, "=") {
+			ref = strings.TrimSpace(rest[1:])
+		}
+	}
+	if len(ref) >= 2 && (ref[0] == '\'' || ref[0] == '"') {
+		if end := strings.IndexByte(ref[1:], ref[0]); end >= 0 {
+			ref = ref[1 : end+1]
+		}
+	}
+	return strings.TrimSpace(ref)
+}
+
+// linkContext returns up to n characters of the text of the link's parent
+// element, centred on the link's own text.
+func linkContext(s *goquery.Selection, n int) string {
+	text := []rune(strings.Join(strings.Fields(s.Parent().Text()), " "))
+	if len(text) <= n {
+		return string(text)
+	}
+	anchor := strings.Join(strings.Fields(s.Text()), " ")
+	start := 0
+	if i := strings.Index(string(text), anchor); i >= 0 && anchor != "" {
+		// Centre the window on the anchor text, converting byte offsets to runes
+		pos := len([]rune(string(text)[:i]))
+		start = pos + len([]rune(anchor))/2 - n/2
+		start = max(0, min(start, len(text)-n))
+	}
+	context := strings.TrimSpace(string(text[start : start+n]))
+	if start > 0 {
+		context = "..." + context
+	}
+	if start+n < len(text) {
+		context += "..."
+	}
+	return context
+}
+
+// parseRel splits a rel attribute into its lower-cased values.
+func parseRel(rel string) []string {
+	return strings.Fields(strings.ToLower(rel))
+}
+
+// hasRel reports whether the link's rel attribute contains value.
+func (l Link) hasRel(value string) bool {
+	for _, r := range l.Rel {
+		if r == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the link for plain-text output.
+func (l Link) String() string {
+	s := l.URL
+	if l.Resolved != "" {
+		s += " -> " + l.Resolved
+	}
+	if len(l.Rel) > 0 {
+		s += " [" + strings.Join(l.Rel, " ") + "]"
+	}
+	if l.Context != "" {
+		s += " \"" + l.Context + "\""
+	}
+	if l.ImageMap != nil {
+		s += " (image map"
+		if l.ImageMap.Alt != "" {
+			s += ": " + l.ImageMap.Alt
+		}
+		s += ")"
+	}
+	return s
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// LocalizedValue is a date or number found in the text of a -parse-dates
+// element, with its normalized form: an ISO 8601 date or a plain decimal
+// number.
+type LocalizedValue struct {
+	Raw   string `json:"raw"`
+	Kind  string `json:"kind"` // date or number
+	Value string `json:"value"`
+}
+
+// localeHint is how a locale writes numbers and numeric dates.
+type localeHint struct {
+	decimal byte   // Decimal separator, or 0 to guess as for prices
+	order   string // Field order of numeric dates: dmy, mdy or ymd
+}
+
+// parseLocale returns the hint for a BCP 47 tag such as "de-DE" or
+// "en-US". An empty or unknown tag guesses the decimal separator and reads
+// numeric dates day first, as most locales do.
+func parseLocale(tag string) (localeHint, error) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	lang, region, _ := strings.Cut(tag, "-")
+	switch lang {
+	case "":
+		return localeHint{order: "dmy"}, nil
+	case "en":
+		switch region {
+		case "", "us", "ph":
+			return localeHint{decimal: '.', order: "mdy"}, nil
+		case "ca":
+			return localeHint{decimal: '.', order: "ymd"}, nil
+		}
+		return localeHint{decimal: '.', order: "dmy"}, nil
+	case "ja", "zh", "ko":
+		return localeHint{decimal: '.', order: "ymd"}, nil
+	case "hu", "lt":
+		return localeHint{decimal: ',', order: "ymd"}, nil
+	case "de", "fr", "es", "it", "nl", "pt", "ru", "pl", "tr", "da", "fi", "nb", "no", "sv", "cs", "sk", "ro", "uk", "el":
+		return localeHint{decimal: ',', order: "dmy"}, nil
+	}
+	return localeHint{}, fmt.Errorf("unknown -locale %q", tag)
+}
+
+// monthNames maps month names and abbreviations in English, German,
+// French, Spanish, Italian, Dutch and Portuguese to their number.
+var monthNames = func() map[string]time.Month {
+	names := [][]string{
+		{"january", "jan", "januar", "jänner", "janvier", "janv", "enero", "ene", "gennaio", "gen", "januari", "janeiro"},
+		{"february", "feb", "februar", "février", "févr", "fevrier", "febrero", "febbraio", "februari", "fevereiro", "fev"},
+		{"march", "mar", "märz", "mär", "mars", "marzo", "maart", "mrt", "março", "marco"},
+		{"april", "apr", "avril", "avr", "abril", "abr", "aprile"},
+		{"may", "mai", "mayo", "maggio", "mag", "mei", "maio"},
+		{"june", "jun", "juni", "juin", "junio", "giugno", "giu", "junho"},
+		{"july", "jul", "juli", "juillet", "juil", "julio", "luglio", "lug", "julho"},
+		{"august", "aug", "août", "aout", "agosto", "ago", "augustus"},
+		{"september", "sep", "sept", "septembre", "septiembre", "settembre", "set", "setembro"},
+		{"october", "oct", "oktober", "okt", "octobre", "octubre", "ottobre", "ott", "outubro", "out"},
+		{"november", "nov", "novembre", "noviembre", "novembro"},
+		{"december", "dec", "dezember", "dez", "décembre", "déc", "decembre", "diciembre", "dic", "dicembre", "dezembro"},
+	}
+	m := make(map[string]time.Month)
+	for i, list := range names {
+		for _, name := range list {
+			m[name] = time.Month(i + 1)
+		}
+	}
+	return m
+}()
+
+// Date and number patterns in running text.
+var (
+	isoDatePattern     = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,4})([./-])(\d{1,2})([./-])(\d{1,4})\b`)
+	dayMonthPattern    = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th|er|º)?\.?\s+(?:de\s+)?(\p{L}+)\.?,?\s+(?:de\s+)?(\d{4})\b`)
+	monthDayPattern    = regexp.MustCompile(`(?i)\b(\p{L}+)\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	numberPattern      = regexp.MustCompile(`[-+]?\d(?:[\d.,'\x{00a0}\x{202f}]*\d)?`)
+)
+
+// extractLocalizedValues finds the dates and numbers in the text of every
+// element matching selector.
+func extractLocalizedValues(doc *goquery.Document, selector string, hint localeHint) []LocalizedValue {
+	var values []LocalizedValue
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		// No-break spaces group digits in many locales, so only ASCII
+		// whitespace is collapsed
+		text := strings.Join(strings.FieldsFunc(s.Text(), func(r rune) bool {
+			return r < utf8.RuneSelf && unicode.IsSpace(r)
+		}), " ")
+		values = append(values, parseLocalized(text, hint)...)
+	})
+	return values
+}
+
+// parseLocalized returns the dates in text and the numbers outside them,
+// in the order they appear.
+func parseLocalized(text string, hint localeHint) []LocalizedValue {
+	type found struct {
+		pos   int
+		value LocalizedValue
+	}
+	var values []found
+	masked := []byte(text)
+	mask := func(loc []int) {
+		for i := loc[0]; i < loc[1]; i++ {
+			masked[i] = ' '
+		}
+	}
+	for _, re := range []*regexp.Regexp{isoDatePattern, dayMonthPattern, monthDayPattern, numericDatePattern} {
+		for _, loc := range re.FindAllStringSubmatchIndex(string(masked), -1) {
+			m := make([]string, len(loc)/2)
+			for i := range m {
+				if loc[2*i] >= 0 {
+					m[i] = string(masked[loc[2*i]:loc[2*i+1]])
+				}
+			}
+			if date, ok := matchDate(re, m, hint); ok {
+				values = append(values, found{loc[0], LocalizedValue{Raw: m[0], Kind: "date", Value: date}})
+				mask(loc)
+			}
+		}
+	}
+
+	for _, loc := range numberPattern.FindAllStringIndex(string(masked), -1) {
+		raw := string(masked[loc[0]:loc[1]])
+		// A sign joined to a word, as in "COVID-19", is a hyphen
+		if (raw[0] == '-' || raw[0] == '+') && loc[0] > 0 && !unicode.IsSpace(rune(masked[loc[0]-1])) {
+			raw = raw[1:]
+		}
+		if n, ok := parseLocalNumber(raw, hint.decimal); ok {
+			values = append(values, found{loc[0], LocalizedValue{Raw: raw, Kind: "number", Value: strconv.FormatFloat(n, 'f', -1, 64)}})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].pos < values[j].pos })
+	result := make([]LocalizedValue, len(values))
+	for i, v := range values {
+		result[i] = v.value
+	}
+	return result
+}
+
+// matchDate converts the submatches of one of the date patterns to an ISO
+// date.
+func matchDate(re *regexp.Regexp, m []string, hint localeHint) (string, bool) {
+	switch re {
+	case isoDatePattern:
+		return isoDate(m[1], m[2], m[3])
+	case dayMonthPattern:
+		month, ok := monthNames[strings.ToLower(m[2])]
+		if !ok {
+			return "", false
+		}
+		return isoDate(m[3], strconv.Itoa(int(month)), m[1])
+	case monthDayPattern:
+		month, ok := monthNames[strings.ToLower(m[1])]
+		if !ok {
+			return "", false
+		}
+		return isoDate(m[3], strconv.Itoa(int(month)), m[2])
+	}
+
+	// Numeric dates need the same separator twice
+	if m[2] != m[4] {
+		return "", false
+	}
+	a, b, c := m[1], m[3], m[5]
+	if len(a) == 4 {
+		return isoDate(a, b, c)
+	}
+	if len(c) != 4 && len(c) != 2 {
+		return "", false
+	}
+	day, month := a, b
+	if hint.order == "mdy" {
+		day, month = b, a
+	}
+	// A field over 12 can only be the day, whatever the locale says
+	if n, _ := strconv.Atoi(month); n > 12 {
+		day, month = month, day
+	}
+	return isoDate(c, month, day)
+}
+
+// isoDate formats a year, month and day as YYYY-MM-DD, rejecting dates that
+// don't exist. Two-digit years are taken as 1970 to 2069.
+func isoDate(year, month, day string) (string, bool) {
+	y, err1 := strconv.Atoi(year)
+	mo, err2 := strconv.Atoi(month)
+	d, err3 := strconv.Atoi(day)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return "", false
+	}
+	if len(year) == 2 {
+		y += 1900
+		if y < 1970 {
+			y += 100
+		}
+	}
+	t := time.Date(y, time.Month(mo), d, 0, 0, 0, 0, time.UTC)
+	if t.Year() != y || int(t.Month()) != mo || t.Day() != d {
+		return "", false
+	}
+	return t.Format("2006-01-02"), true
+}
+
+// parseLocalNumber parses a number with grouping and decimal separators.
+// decimal is the locale's decimal separator, which settles whether "1.234"
+// is a thousand or a fraction; without one it is guessed as for prices.
+func parseLocalNumber(raw string, decimal byte) (float64, bool) {
+	num := strings.Map(func(r rune) rune {
+		if r == '\'' || r == '\u00a0' || r == '\u202f' {
+			return -1
+		}
+		return r
+	}, raw)
+	n, err := strconv.ParseFloat(normalizeNumber(num, decimal), 64)
+	return n, err == nil
+}
+
+// String formats the value for plain-text output.
+func (v LocalizedValue) String() string {
+	return fmt.Sprintf("%s = %s (%s)", v.Raw, v.Value, v.Kind)
+}
@@ -2,141 +2,1031 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 // ScrapeData holds the scraped information from a webpage.
 type ScrapeData struct {
-	Links  []string // URLs from <a> tags
-	Texts  []string // Text from <p> tags
-	Images []string // Src from <img> tags
+	assets    map[string][]string // Script and stylesheet URLs, with -page-weight
+	canonical string              // Absolute URL of <link rel="canonical">
+
+	URL    string `json:"url,omitempty"`    // Page the data was scraped from
+	Status int    `json:"status,omitempty"` // HTTP status of the response
+	Title  string `json:"title,omitempty"`  // Text of the <title> element
+
+	Author    []Attribution `json:"author,omitempty"`    // Authors from JSON-LD, meta tags or rel="author" links
+	Publisher *Attribution  `json:"publisher,omitempty"` // Publisher from JSON-LD or meta tags
+	ScrapedAt time.Time     `json:"scraped_at"`
+
+	Links            []Link            `json:"links"`                       // URLs from <a> tags
+	Texts            []TextEntry       `json:"texts"`                       // Text from <p> tags
+	Images           []string          `json:"images"`                      // Src from <img> tags
+	DownloadedImages []DownloadedImage `json:"downloaded_images,omitempty"` // Distinct images saved by -download-images and the URLs serving each
+	InlineImages     []InlineImage     `json:"inline_images,omitempty"`     // <img> tags whose src is a data: URI
+
+	ContentHash       string                 `json:"content_hash,omitempty"`        // SHA-256 of the extracted text
+	LastModified      *time.Time             `json:"last_modified,omitempty"`       // From the Last-Modified header or page metadata
+	Accessibility     map[string][]A11yEntry `json:"accessibility,omitempty"`       // ARIA attributes by tag, with -a11y
+	Selections        map[string][]string    `json:"selections,omitempty"`          // Values matched by -select rules
+	IFrames           []string               `json:"iframes,omitempty"`             // Absolute src of <iframe> tags
+	Media             map[string][]string    `json:"media,omitempty"`               // Audio, video and poster URLs, with -media, and background images, with -bg-images
+	Recipe            *Recipe                `json:"recipe,omitempty"`              // schema.org Recipe data, with -recipe
+	Product           *Product               `json:"product,omitempty"`             // schema.org Product data, with -product
+	MainText          []string               `json:"main_text,omitempty"`           // Main article paragraphs, with -readability
+	PrintURL          string                 `json:"print_url,omitempty"`           // Absolute URL of the print-friendly version, from rel="alternate" media="print" or a ?print=1 link
+	AMPURL            string                 `json:"amp_url,omitempty"`             // Absolute URL of the AMP version from <link rel="amphtml">
+	Warnings          []string               `json:"warnings,omitempty"`            // Problems that may make the data incomplete
+	Cookies           []Cookie               `json:"cookies,omitempty"`             // Cookies set by the response
+	BrokenAnchors     []string               `json:"broken_anchors,omitempty"`      // In-page #fragment links with no target, with -check-anchors
+	ResourceHints     []ResourceHint         `json:"resource_hints,omitempty"`      // preconnect, preload, prefetch and dns-prefetch links, with -resource-hints
+	ImageDimensions   map[string]Dimensions  `json:"image_dimensions,omitempty"`    // Image sizes by src, with -image-dims
+	ThirdPartyScripts map[string]int         `json:"third_party_scripts,omitempty"` // External script hosts and how many scripts each serves, with -third-party-scripts
+	Tables            []Table                `json:"tables,omitempty"`              // Table grids, captions and header-keyed rows, with -tables
+	Times             []TimeEntry            `json:"times,omitempty"`               // <time> elements, with -times
+	Headings          []Heading              `json:"headings,omitempty"`            // Headings with their anchors, with -headings
+	Alternates        map[string]string      `json:"alternates,omitempty"`          // hreflang codes and their localized URLs
+	Encoding          *Encoding              `json:"encoding,omitempty"`            // Charset the page was decoded with and how it was chosen
+	Errors            []PageError            `json:"errors,omitempty"`              // Pages of a crawl that failed
+	LinkGraph         map[string][]string    `json:"link_graph,omitempty"`          // Pages of a crawl and the pages each links to, with -format dot
+	ESDocuments       []ESDocument           `json:"es_documents,omitempty"`        // A document per page of a crawl, with -format es-bulk
+	ParquetRows       []ParquetRow           `json:"parquet_rows,omitempty"`        // The items of each page of a crawl, with -format parquet
+	CanonicalFrom     string                 `json:"canonical_from,omitempty"`      // URL whose <link rel="canonical"> led here, with -follow-canonical
+	RefreshURL        string                 `json:"refresh_url,omitempty"`         // Target of a <meta http-equiv="refresh"> redirect
+	LocalizedValues   []LocalizedValue       `json:"localized_values,omitempty"`    // Dates and numbers normalized from text, with -parse-dates
+	Prices            []Price                `json:"prices,omitempty"`              // Normalized prices, with -parse-prices
+	FAQ               []QA                   `json:"faq,omitempty"`                 // FAQ questions and answers, with -faq
+	DOMMetrics        *DOMMetrics            `json:"dom_metrics,omitempty"`         // Element counts and nesting depth, with -dom-metrics
+	Inconsistencies   []Inconsistency        `json:"inconsistencies,omitempty"`     // Metadata sources that disagree, with -check-consistency
+	PageWeight        *PageWeight            `json:"page_weight,omitempty"`         // Size of images, scripts and stylesheets, with -page-weight
+	Videos            []Video                `json:"videos,omitempty"`              // schema.org VideoObject or og:video data, with -video
+	Breadcrumbs       []Crumb                `json:"breadcrumbs,omitempty"`         // The page's breadcrumb trail, with -breadcrumbs
+	SiteTree          []*SiteNode            `json:"site_tree,omitempty"`           // Site structure built from the breadcrumbs of every crawled page, with -breadcrumbs
+	Events            []Event                `json:"events,omitempty"`              // schema.org Event data, with -event
+	Profile           []StageTime            `json:"profile,omitempty"`             // Time spent fetching, parsing and in each extractor, with -profile; summed over a crawl
+	Truncated         bool                   `json:"truncated,omitempty"`           // The connection dropped before the whole page was read
+	Rating            *AggregateRating       `json:"rating,omitempty"`              // schema.org AggregateRating or star-widget rating, with -rating
+	Templates         []EmbeddedContent      `json:"templates,omitempty"`           // Markup inside <template> and <noscript>, with -include-templates
+	ImageSources      map[string][]string    `json:"image_sources,omitempty"`       // Pages each image was found on, with -merge
+	MergedFrom        []MergeSource          `json:"merged_from,omitempty"`         // Result files combined with -merge
+	Viewport          *Viewport              `json:"viewport,omitempty"`            // Parsed <meta name="viewport">, with -viewport
+}
+
+// Options holds the command-line settings that control scraping.
+type Options struct {
+	Depth               int               // How many links deep to crawl from the start URL
+	MaxURLs             int               // Maximum number of pages to fetch while crawling
+	SkipNofollow        bool              // Don't follow rel="nofollow" links while crawling
+	StateFile           string            // File the crawl state is saved to
+	Resume              bool              // Continue a crawl from StateFile
+	PauseFile           string            // While this file exists the crawl starts no new pages
+	PageParam           string            // Query parameter used for page numbers
+	PageRange           string            // Pages to scrape, e.g. "1-10"
+	PageStart           int               // First value of -page-param with -page-step
+	PageStep            int               // Increment of -page-param, for offset pagination
+	MaxPages            int               // Most pages to scrape when paginating
+	WithPath            bool              // Record the source element path of each text
+	Method              string            // HTTP method used for the requested URL
+	Data                string            // Form-encoded request body
+	JSONBody            string            // JSON request body
+	Repeat              int               // Scrape the URL this many times and report metrics
+	Stream              bool              // Write items to the output file as they are found
+	A11y                bool              // Collect ARIA roles and labels
+	Selects             []selectRule      // Named extraction rules from -select
+	ExpandURLs          bool              // Resolve the final destination of every link
+	Delay               time.Duration     // Minimum delay between requests to the same host
+	CountOnly           bool              // Print only the number of items per category
+	FollowIFrames       bool              // Scrape same-origin iframes and merge their data
+	Headers             map[string]string // Extra request headers
+	WithHTML            bool              // Keep the inner HTML of each text element
+	Media               bool              // Collect audio and video sources
+	Quiet               bool              // Don't print results or prompt; save straight to the output file
+	Product             bool              // Extract schema.org Product data
+	Recipe              bool              // Extract schema.org Recipe data
+	Resolve             map[string]string // Hosts pinned to an IP address with -resolve
+	Readability         bool              // Extract the main content without boilerplate
+	PreferAMP           bool              // Scrape the AMP version of a page when it has one
+	PreferPrint         bool              // Scrape the print version of a page when it has one
+	PageTimeout         time.Duration     // Limit on the total time spent on each page
+	CheckAnchors        bool              // Report in-page fragment links with no target
+	MaxConnections      int               // Limit on simultaneous requests across all hosts
+	ResourceHints       bool              // Collect preconnect, preload and prefetch hints
+	UserAgent           string            // User-Agent header sent with requests
+	UserAgentFile       string            // File of user agents to rotate through
+	UserAgentRotation   string            // round-robin or random
+	ImageDims           bool              // Read image dimensions with partial requests
+	Clipboard           bool              // Copy the output to the clipboard instead of a file
+	RespectRobots       bool              // Skip URLs disallowed by robots.txt and honor its Crawl-delay
+	ThirdPartyScripts   bool              // Count scripts loaded from other hosts
+	DownloadImages      string            // Directory to save the page images in
+	AllowDomains        []string          // Other domains the crawl may follow links to
+	HostDepth           map[string]int    // Crawl depth limits per host, from -host-depth
+	Tables              bool              // Extract tables
+	TokensFile          string            // File of bearer tokens to rotate through
+	Times               bool              // Extract <time> elements
+	Headings            bool              // Extract headings and their anchors
+	ExcludeSelectors    []string          // Elements removed before extraction
+	FlushEvery          int               // Pages between writes of crawl results to the output file
+	OnlyErrors          bool              // Report only the URLs that failed
+	BgImages            bool              // Collect CSS background images
+	LinkGraph           bool              // Record which crawled pages link to which
+	ESDocuments         bool              // Keep a search document for each crawled page
+	ParquetRows         bool              // Keep the Parquet rows of each crawled page
+	JSONPaths           []string          // Paths to extract from a JSON response instead of parsing HTML
+	FollowMetaRefresh   bool              // Scrape the target of a meta refresh instead
+	FollowCanonical     bool              // Scrape a page's same-host canonical URL in its place
+	metaRefreshes       int               // Meta refreshes followed to reach the current page
+	profile             *pageProfile      // Stage timings of the current page, with -profile
+	refetch             bool              // Retrying a soft failure: fetch past the cache
+	DownloadConcurrency int               // Image downloads in flight at a time
+	LinkContext         int               // Characters of surrounding text kept with each link
+	UseCache            bool              // Serve responses from the disk cache when present
+	CacheDir            string            // Directory of the response cache
+	CacheTTL            time.Duration     // Age after which cached responses are refetched
+	LogRequests         string            // File every request sent is logged to
+	LogSecrets          bool              // Keep auth headers in the request log
+	ParsePrices         string            // Selector of elements to parse as prices
+	ParseDates          string            // Selector of elements whose text dates and numbers are normalized
+	Locale              string            // Locale hint for -parse-dates, e.g. de-DE
+	SeenFile            string            // File of links seen by earlier runs; only new links are output
+	FAQ                 bool              // Extract FAQ questions and answers
+	Retries             int               // Times a failed request is retried
+	MaxTotalRetries     int               // Retries allowed across the whole run; 0 is unlimited
+	RetryBodyMatch      *regexp.Regexp    // Response bodies that are retried as soft failures
+	DOMMetrics          bool              // Compute element counts and DOM depth
+	Profile             bool              // Time the fetch, parse and each extractor of every page
+	CheckConsistency    bool              // Compare title, canonical URL and description sources
+	HAR                 string            // HAR file to replay responses from instead of fetching
+	Strict              string            // Check markup for duplicate IDs and malformed attributes: warn or fail
+	ClientCert          string            // PEM certificate for mutual TLS
+	ClientKey           string            // PEM private key of -client-cert
+	PageWeight          bool              // Total the size of the page's assets by type
+	SampleRate          float64           // Fraction of crawled pages whose data is kept
+	Seed                int64             // Seed of the -sample-rate and -crawl-order random RNG; 0 picks one
+	CrawlOrder          string            // Order the crawl frontier is visited in: bfs, dfs or random
+	Video               bool              // Extract VideoObject and og:video metadata
+	AcceptStatus        []statusRange     // Status codes whose pages are parsed besides 200
+	Rating              bool              // Extract the aggregate rating
+	Event               bool              // Extract schema.org Event data
+	Breadcrumbs         bool              // Extract breadcrumb trails and build a site tree from them
+	Sign                string            // Request signing scheme, e.g. aws-sigv4
+	SignService         string            // Service name signed into -sign aws-sigv4 requests
+	SignRegion          string            // Region signed into -sign aws-sigv4 requests
+	StripTracking       bool              // Remove utm_*, fbclid and similar parameters from links
+	IncludeTemplates    bool              // Extract <template> and <noscript> content
+	Viewport            bool              // Parse the viewport meta tag
+}
+
+// maxMetaRefreshes is how many meta refresh redirects are followed in a row
+// with -follow-meta-refresh.
+const maxMetaRefreshes = 5
+
+// statusError is returned by scrapePage when the server responds with a
+// status other than 200 OK or one accepted by -accept-status.
+type statusError struct {
+	Code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("error: status code %d", e.Code)
 }
 
 // scrapePage fetches and scrapes a webpage, returning collected data.
-func scrapePage(url string) (ScrapeData, error) {
-	// Make the HTTP request
-	resp, err := http.Get(url)
-	if err != nil {
-		return ScrapeData{}, fmt.Errorf("error fetching URL: %v", err)
+func scrapePage(ctx context.Context, url string, opts Options) (ScrapeData, error) {
+	return scrapePageTo(ctx, url, opts, nil)
+}
+
+// scrapePageTo is like scrapePage, but when out is non-nil the links, texts
+// and images are written to out as they are found instead of being collected.
+func scrapePageTo(ctx context.Context, url string, opts Options, out itemWriter) (ScrapeData, error) {
+	if opts.PageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.PageTimeout)
+		defer cancel()
+	}
+	if opts.Profile {
+		opts.profile = newPageProfile()
+	}
+
+	var resp *http.Response
+	var data ScrapeData
+	for attempt := 0; ; attempt++ {
+		// Make the HTTP request
+		var err error
+		resp, err = fetchPageRetrying(ctx, url, opts)
+		if err != nil {
+			return ScrapeData{}, err
+		}
+		opts.profile.lap("fetch")
+		defer resp.Body.Close()
+
+		// Check for successful response
+		if !acceptStatus(resp.StatusCode, opts) {
+			return ScrapeData{}, &statusError{Code: resp.StatusCode}
+		}
+
+		// JSON endpoints are queried instead of parsed as HTML
+		if len(opts.JSONPaths) > 0 {
+			values, err := parseJSONPaths(resp.Body, opts.JSONPaths)
+			if err != nil {
+				return ScrapeData{}, err
+			}
+			return ScrapeData{URL: resp.Request.URL.String(), ScrapedAt: time.Now(), Selections: values}, nil
+		}
+
+		body := &truncationReader{r: opts.profile.reader(resp.Body)}
+		data, err = parsePageTo(body, resp.Header.Get("Content-Type"), resp.Request.URL, opts, out)
+		// Release the connection before any follow-up requests
+		resp.Body.Close()
+		if err != nil {
+			return data, err
+		}
+		if body.err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return data, fmt.Errorf("error parsing HTML: %v", body.err)
+		}
+
+		// The connection dropped mid-body: fetch the page again unless its
+		// items were already streamed or no retries are left, otherwise keep
+		// what was read
+		if out == nil && attempt < opts.Retries && totalRetries.take() {
+			delay := retryDelay(attempt)
+			log.Printf("%s: response body cut short (%v), retrying in %s", url, body.err, delay)
+			select {
+			case <-time.After(delay):
+				continue
+			case <-ctx.Done():
+				return data, fmt.Errorf("error fetching URL: %v", ctx.Err())
+			}
+		}
+		warning := fmt.Sprintf("response body cut short: %v; the data is incomplete", body.err)
+		log.Printf("%s: %s", url, warning)
+		data.Truncated = true
+		data.Warnings = append(data.Warnings, warning)
+		break
+	}
+	data.URL = resp.Request.URL.String()
+	data.Status = resp.StatusCode
+	data.ScrapedAt = time.Now()
+	data.Cookies = responseCookies(resp)
+
+	// Switch to the AMP version; not possible once items have been streamed
+	if opts.PreferAMP && out == nil && data.AMPURL != "" && data.AMPURL != resp.Request.URL.String() {
+		ampOpts := followOptions(opts)
+		ampOpts.PreferAMP = false
+		amp, err := scrapePage(ctx, data.AMPURL, ampOpts)
+		if err == nil {
+			amp.AMPURL = data.AMPURL
+			return amp, nil
+		}
+		log.Printf("Failed to scrape AMP version %s: %v", data.AMPURL, err)
+	}
+
+	// Likewise the print version, which usually has less boilerplate
+	if opts.PreferPrint && out == nil && data.PrintURL != "" && data.PrintURL != resp.Request.URL.String() {
+		printOpts := followOptions(opts)
+		printOpts.PreferPrint = false
+		printed, err := scrapePage(ctx, data.PrintURL, printOpts)
+		if err == nil {
+			printed.PrintURL = data.PrintURL
+			return printed, nil
+		}
+		log.Printf("Failed to scrape print version %s: %v", data.PrintURL, err)
+	}
+
+	// Follow a meta refresh like an HTTP redirect, within the same limit of hops
+	if opts.FollowMetaRefresh && out == nil && data.RefreshURL != "" && data.RefreshURL != resp.Request.URL.String() {
+		if opts.metaRefreshes >= maxMetaRefreshes {
+			log.Printf("%s: not following meta refresh to %s after %d refreshes", url, data.RefreshURL, opts.metaRefreshes)
+		} else {
+			nextOpts := followOptions(opts)
+			nextOpts.metaRefreshes++
+			next, err := scrapePage(ctx, data.RefreshURL, nextOpts)
+			if err == nil {
+				next.RefreshURL = data.RefreshURL
+				return next, nil
+			}
+			log.Printf("Failed to follow meta refresh to %s: %v", data.RefreshURL, err)
+		}
 	}
-	defer resp.Body.Close()
 
-	// Check for successful response
-	if resp.StatusCode != http.StatusOK {
-		return ScrapeData{}, fmt.Errorf("error: status code %d", resp.StatusCode)
+	// Scrape the canonical version in place of a variant, like a redirect
+	if opts.FollowCanonical && out == nil {
+		if target, ok := canonicalTarget(data); ok {
+			canonicalOpts := followOptions(opts)
+			canonicalOpts.FollowCanonical = false
+			canonical, err := scrapePage(ctx, target, canonicalOpts)
+			if err == nil {
+				canonical.CanonicalFrom = data.URL
+				return canonical, nil
+			}
+			log.Printf("Failed to scrape canonical version %s: %v", target, err)
+		}
+	}
+
+	if opts.FollowIFrames {
+		data = scrapeIFrames(ctx, data, resp.Request.URL, opts, out)
+		opts.profile.lap("iframes")
+	}
+
+	if opts.ExpandURLs {
+		expandLinks(ctx, data.Links)
+		if opts.StripTracking {
+			// Redirects often add tracking parameters of their own
+			for i := range data.Links {
+				if data.Links[i].Resolved != "" {
+					data.Links[i].Resolved = stripTracking(data.Links[i].Resolved)
+				}
+			}
+		}
+		opts.profile.lap("expand_urls")
 	}
 
-	// Load HTML into goquery
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if opts.ImageDims || opts.PageWeight {
+		prefetchImages(ctx, data.Images, resp.Request.URL)
+		opts.profile.lap("image_probes")
+	}
+	if opts.PageWeight {
+		data.PageWeight = pageWeight(ctx, data.Images, data.assets, resp.Request.URL)
+		opts.profile.lap("page_weight")
+	}
+	if opts.ImageDims {
+		data.ImageDimensions = imageDimensions(ctx, data.Images, resp.Request.URL)
+	}
+	if opts.DownloadImages != "" {
+		data.DownloadedImages = downloadImages(ctx, data.Images, resp.Request.URL, opts.DownloadImages, opts.DownloadConcurrency)
+		saveInlineImages(data.InlineImages, opts.DownloadImages)
+		opts.profile.lap("download_images")
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		warning := fmt.Sprintf("page timeout of %s exceeded, results may be incomplete", opts.PageTimeout)
+		log.Printf("%s: %s", url, warning)
+		data.Warnings = append(data.Warnings, warning)
+	}
+
+	// The Last-Modified header takes precedence over page metadata
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		data.LastModified = &t
+	}
+	data.Profile = opts.profile.result()
+	return data, nil
+}
+
+// parsePage parses the HTML read from r and extracts data from it. Relative
+// URLs are resolved against base, which may be nil.
+func parsePage(r io.Reader, base *url.URL, opts Options) (ScrapeData, error) {
+	return parsePageTo(r, "", base, opts, nil)
+}
+
+// parsePageTo is like parsePage, but writes links, texts and images to out
+// when it is non-nil. contentType is the response Content-Type, used to pick
+// the character encoding.
+func parsePageTo(r io.Reader, contentType string, base *url.URL, opts Options, out itemWriter) (ScrapeData, error) {
+	// Load HTML into goquery, decoding it to UTF-8 first
+	r, enc := decodeCharset(r, contentType)
+	var raw bytes.Buffer
+	if opts.Strict != "" {
+		r = io.TeeReader(r, &raw)
+	}
+	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return ScrapeData{}, fmt.Errorf("error parsing HTML: %v", err)
 	}
+	opts.profile.lap("parse")
+	var markupProblems []string
+	if opts.Strict != "" {
+		markupProblems = append(checkDuplicateIDs(doc), checkAttributes(raw.Bytes())...)
+		opts.profile.lap("strict")
+	}
+	page := base
+	base = documentBase(doc, base)
+	for _, selector := range opts.ExcludeSelectors {
+		doc.Find(selector).Remove()
+	}
 
 	// Collect data
-	data := ScrapeData{}
+	data := ScrapeData{Encoding: &enc, Warnings: markupProblems}
+	stream := out
+	if out == nil {
+		out = &data
+	}
+	var writeErr error
+	emit := func(kind string, value any) {
+		if writeErr == nil {
+			writeErr = out.WriteItem(kind, value)
+		}
+	}
 
 	// Extract links from <a> tags
 	doc.Find("a").Each(func(i int, s *goquery.Selection) {
 		if href, exists := s.Attr("href"); exists && strings.HasPrefix(href, "http") {
-			data.Links = append(data.Links, href)
+			rel, _ := s.Attr("rel")
+			if opts.StripTracking {
+				href = stripTracking(href)
+			}
+			link := Link{URL: href, Rel: parseRel(rel)}
+			if opts.LinkContext > 0 {
+				link.Context = linkContext(s, opts.LinkContext)
+			}
+			emit("link", link)
 		}
 	})
 
+	// Extract image-map regions from <area> tags
+	doc.Find("area[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		abs, ok := resolveURL(base, href)
+		if !ok || !strings.HasPrefix(abs, "http") {
+			return
+		}
+		if opts.StripTracking {
+			abs = stripTracking(abs)
+		}
+		rel, _ := s.Attr("rel")
+		alt, _ := s.Attr("alt")
+		shape, _ := s.Attr("shape")
+		coords, _ := s.Attr("coords")
+		emit("link", Link{URL: abs, Rel: parseRel(rel), ImageMap: &MapArea{
+			Alt:    strings.TrimSpace(alt),
+			Shape:  strings.ToLower(strings.TrimSpace(shape)),
+			Coords: strings.TrimSpace(coords),
+		}})
+	})
+	opts.profile.lap("links")
+
 	// Extract text from <p> tags
 	doc.Find("p").Each(func(i int, s *goquery.Selection) {
 		text := strings.TrimSpace(s.Text())
 		if text != "" {
-			data.Texts = append(data.Texts, text)
+			entry := TextEntry{
+				Text:      text,
+				Lang:      inheritedAttr(s, "lang"),
+				Dir:       inheritedAttr(s, "dir"),
+				WordCount: len(strings.Fields(text)),
+			}
+			if opts.WithPath {
+				entry.Path = elementPath(s)
+			}
+			if opts.WithHTML {
+				if html, err := s.Html(); err == nil {
+					entry.HTML = strings.TrimSpace(html)
+				}
+			}
+			emit("text", entry)
 		}
 	})
+	opts.profile.lap("texts")
 
 	// Extract image sources from <img> tags
 	doc.Find("img").Each(func(i int, s *goquery.Selection) {
 		if src, exists := s.Attr("src"); exists {
-			data.Images = append(data.Images, src)
+			// Inline images are decoded rather than kept as huge strings
+			if img, ok := parseDataImage(src); ok {
+				emit("image", img)
+			} else if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(src)), "data:") {
+				emit("image", src)
+			}
 		}
 	})
+	opts.profile.lap("images")
 
-	return data, nil
-}
-
-// saveToFile writes the scraped data to a file.
-func saveToFile(data ScrapeData, filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("error creating file: %v", err)
+	data.Title = strings.Join(strings.Fields(doc.Find("title").First().Text()), " ")
+	data.Author = extractAuthors(doc, base)
+	data.Publisher = extractPublisher(doc, base)
+	data.LastModified = extractLastModified(doc)
+	data.IFrames = extractIFrames(doc, base)
+	data.canonical = extractCanonical(doc, base)
+	if href, ok := doc.Find(`link[rel~="amphtml"]`).First().Attr("href"); ok {
+		data.AMPURL, _ = resolveURL(base, href)
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	fmt.Fprintln(writer, "Scraped Links:")
-	for i, link := range data.Links {
-		fmt.Fprintf(writer, "%d. %s\n", i+1, link)
+	data.PrintURL = extractPrintURL(doc, base)
+	data.Alternates = extractAlternates(doc, base)
+	data.RefreshURL = extractMetaRefresh(doc, base)
+	opts.profile.lap("metadata")
+	if opts.A11y {
+		data.Accessibility = extractAccessibility(doc)
+		opts.profile.lap("a11y")
 	}
-
-	fmt.Fprintln(writer, "\nScraped Text (Paragraphs):")
-	for i, text := range data.Texts {
-		fmt.Fprintf(writer, "%d. %s\n", i+1, text)
+	if opts.Media {
+		data.Media = extractMedia(doc, base)
+		opts.profile.lap("media")
 	}
-
-	fmt.Fprintln(writer, "\nScraped Images:")
-	for i, src := range data.Images {
-		fmt.Fprintf(writer, "%d. %s\n", i+1, src)
+	if opts.BgImages {
+		if images := extractBackgroundImages(doc, base); len(images) > 0 {
+			if data.Media == nil {
+				data.Media = make(map[string][]string)
+			}
+			data.Media["background"] = images
+		}
+		opts.profile.lap("bg_images")
+	}
+	if opts.CheckAnchors {
+		data.BrokenAnchors = checkAnchors(doc, base)
+		opts.profile.lap("check_anchors")
+	}
+	if opts.ResourceHints {
+		data.ResourceHints = extractResourceHints(doc, base)
+		opts.profile.lap("resource_hints")
+	}
+	if opts.ThirdPartyScripts {
+		data.ThirdPartyScripts = extractThirdPartyScripts(doc, page, base)
+		opts.profile.lap("third_party_scripts")
+	}
+	if opts.Headings {
+		data.Headings = extractHeadings(doc, page)
+		opts.profile.lap("headings")
+	}
+	if opts.Times {
+		data.Times = extractTimes(doc)
+		opts.profile.lap("times")
+	}
+	if opts.Tables {
+		data.Tables = extractTables(doc)
+		opts.profile.lap("tables")
+	}
+	if opts.Readability {
+		data.MainText = extractMainText(doc)
+		opts.profile.lap("readability")
+	}
+	if opts.Product {
+		data.Product = extractProduct(doc)
+		opts.profile.lap("product")
+	}
+	if opts.Recipe {
+		data.Recipe = extractRecipe(doc)
+		opts.profile.lap("recipe")
+	}
+	if opts.Viewport {
+		if data.Viewport = extractViewport(doc); data.Viewport == nil {
+			data.Warnings = append(data.Warnings, missingViewport)
+		}
+		opts.profile.lap("viewport")
+	}
+	if opts.Rating {
+		data.Rating = extractRating(doc)
+		opts.profile.lap("rating")
+	}
+	if opts.Video {
+		data.Videos = extractVideos(doc, base)
+		opts.profile.lap("video")
+	}
+	if opts.Event {
+		data.Events = extractEvents(doc)
+		opts.profile.lap("event")
+	}
+	if opts.Breadcrumbs {
+		data.Breadcrumbs = extractBreadcrumbs(doc, base)
+		pageURL := ""
+		if page != nil {
+			pageURL = normalizeURL(page)
+		}
+		data.SiteTree = addTrail(nil, siteTrail(data.Breadcrumbs, pageURL, data.Title))
+		opts.profile.lap("breadcrumbs")
+	}
+	if opts.CheckConsistency {
+		data.Inconsistencies = checkConsistency(doc, page, base)
+		opts.profile.lap("check_consistency")
+	}
+	if opts.PageWeight {
+		data.assets = extractAssets(doc, base)
+		opts.profile.lap("page_weight_assets")
+	}
+	if opts.DOMMetrics {
+		data.DOMMetrics = extractDOMMetrics(doc)
+		opts.profile.lap("dom_metrics")
+	}
+	if opts.FAQ {
+		data.FAQ = extractFAQ(doc)
+		opts.profile.lap("faq")
+	}
+	if opts.ParseDates != "" {
+		// -locale was validated at startup
+		hint, _ := parseLocale(opts.Locale)
+		data.LocalizedValues = extractLocalizedValues(doc, opts.ParseDates, hint)
+		opts.profile.lap("parse_dates")
+	}
+	if opts.ParsePrices != "" {
+		data.Prices = extractPrices(doc, opts.ParsePrices)
+		opts.profile.lap("parse_prices")
+	}
+	if len(opts.Selects) > 0 {
+		data.Selections = extractSelections(doc, opts.Selects)
+		opts.profile.lap("selects")
 	}
 
-	return writer.Flush()
+	if opts.IncludeTemplates && writeErr == nil {
+		var noscript []string
+		data.Templates, noscript = extractTemplates(doc)
+		if data, err = parseNoscript(noscript, base, data, opts, stream); err != nil {
+			return data, err
+		}
+		opts.profile.lap("templates")
+	}
+	if opts.FollowIFrames && writeErr == nil {
+		if data, err = parseSrcdocFrames(doc, page, data, opts, stream); err != nil {
+			return data, err
+		}
+		opts.profile.lap("srcdoc_frames")
+	}
+	if opts.Strict == "fail" && len(markupProblems) > 0 {
+		return data, fmt.Errorf("-strict: %d markup problems, first: %s", len(markupProblems), markupProblems[0])
+	}
+	if writeErr != nil {
+		return data, fmt.Errorf("error writing output: %v", writeErr)
+	}
+	return data, nil
 }
 
 func main() {
 	// Parse URL flag
 	url := flag.String("url", "", "URL to scrape (e.g., https://example.com)")
 	output := flag.String("output", "output.txt", "File to save scraped data (if saved)")
+	format := flag.String("format", "text", "Format of the saved file: text, json, jsonl (one line per item), html, parquet, dot (crawl link graph) or es-bulk (Elasticsearch _bulk body)")
+	diffFile := flag.String("diff", "", "Previous JSON result to compare the content against")
+	var opts Options
+	flag.IntVar(&opts.Depth, "depth", 0, "How many links deep to crawl on the same host (0 scrapes only the URL)")
+	flag.IntVar(&opts.MaxURLs, "max-urls", 0, "Maximum number of pages to fetch while crawling (0 for no limit)")
+	flag.BoolVar(&opts.SkipNofollow, "skip-nofollow", false, "Don't follow rel=\"nofollow\" links while crawling")
+	flag.StringVar(&opts.StateFile, "state", "", "File to periodically save crawl progress to")
+	flag.StringVar(&opts.PauseFile, "pause-file", "", "Pause the crawl while this file exists, finishing the page in flight and saving -state; remove it to resume")
+	flag.BoolVar(&opts.Resume, "resume", false, "Resume an interrupted crawl from the -state file")
+	flag.StringVar(&opts.PageParam, "page-param", "", "Query parameter to paginate through (e.g., page)")
+	flag.StringVar(&opts.PageRange, "page-range", "1-10", "Range of page numbers to scrape with -page-param")
+	flag.IntVar(&opts.PageStart, "page-start", 0, "First value of -page-param when -page-step is set")
+	flag.IntVar(&opts.PageStep, "page-step", 0, "Increment -page-param by this step from -page-start instead of using -page-range (e.g. offset=0,20,40...)")
+	flag.IntVar(&opts.MaxPages, "max-pages", 0, fmt.Sprintf("Maximum pages to scrape with -page-param (default %d with -page-step, unlimited with -page-range)", defaultMaxPages))
+	flag.BoolVar(&opts.WithPath, "with-path", false, "Record the source element path of each text")
+	flag.StringVar(&opts.Method, "method", "GET", "HTTP method to use for the requested URL (e.g., POST); crawled links are fetched with GET")
+	flag.StringVar(&opts.Data, "data", "", "Form-encoded request body (e.g., q=go&page=2)")
+	flag.StringVar(&opts.JSONBody, "json-body", "", "JSON request body")
+	flag.IntVar(&opts.Repeat, "repeat", 0, "Scrape the URL N times and report throughput and latency metrics")
+	flag.BoolVar(&opts.Stream, "stream-output", false, "Write items to the output file (- for stdout) as they are found instead of collecting them")
+	flag.BoolVar(&opts.A11y, "a11y", false, "Collect ARIA roles and labels for accessibility audits")
+	var headers stringList
+	flag.Var(&headers, "header", "Extra request header \"Name: value\" (repeatable)")
+	jobsFile := flag.String("jobs-file", "", "JSON-lines file of per-URL jobs (url, selectors, headers, depth)")
+	var resolves stringList
+	flag.Var(&resolves, "resolve", "Pin a host to an IP address, host:ip (repeatable)")
+	var selects stringList
+	flag.Var(&selects, "select", "Named extraction rule name=selector[@attr] (repeatable)")
+	flag.BoolVar(&opts.ExpandURLs, "expand-urls", false, "Follow redirects of each link and record its final URL")
+	flag.DurationVar(&opts.Delay, "delay", 0, "Minimum delay between requests to the same host (e.g., 500ms)")
+	flag.BoolVar(&opts.CountOnly, "count-only", false, "Print only the number of items per category")
+	flag.BoolVar(&opts.FollowIFrames, "follow-iframes", false, "Scrape same-origin and srcdoc iframe documents with the same selectors and merge their data")
+	flag.BoolVar(&opts.WithHTML, "with-html", false, "Keep the inner HTML of each text element alongside its text")
+	flag.BoolVar(&opts.Media, "media", false, "Collect <audio> and <video> sources and poster images")
+	flag.BoolVar(&opts.Quiet, "quiet", false, "Don't print results or prompt; save straight to the -output file")
+	flag.BoolVar(&opts.Recipe, "recipe", false, "Extract schema.org Recipe data (ingredients, instructions, times, yield, nutrition) from JSON-LD or microdata")
+	flag.BoolVar(&opts.Product, "product", false, "Extract schema.org Product data (name, price, availability, ...)")
+	flag.BoolVar(&opts.Readability, "readability", false, "Extract the main article text without navigation and footer boilerplate")
+	flag.BoolVar(&opts.PreferAMP, "prefer-amp", false, "Scrape the AMP version of a page instead when it links to one")
+	flag.BoolVar(&opts.PreferPrint, "prefer-print", false, "Scrape the print-friendly version of a page instead when it links to one")
+	flag.DurationVar(&opts.PageTimeout, "page-timeout", 0, "Limit on the total time spent on each page, including sub-requests (e.g., 30s)")
+	flag.BoolVar(&opts.CheckAnchors, "check-anchors", false, "Report in-page #fragment links that point to no element")
+	flag.IntVar(&opts.MaxConnections, "max-connections", 0, "Maximum simultaneous HTTP requests across all hosts (0 for no limit)")
+	flag.BoolVar(&opts.ResourceHints, "resource-hints", false, "Collect preconnect, preload, prefetch and dns-prefetch hints")
+	flag.StringVar(&opts.UserAgent, "user-agent", "", "User-Agent header to send")
+	flag.StringVar(&opts.UserAgentFile, "user-agent-file", "", "File of user agents, one per line, to rotate through per request")
+	flag.StringVar(&opts.UserAgentRotation, "user-agent-rotation", "round-robin", "How to rotate user agents: round-robin or random")
+	flag.BoolVar(&opts.ImageDims, "image-dims", false, "Read each image's width and height with a partial request")
+	flag.BoolVar(&opts.Clipboard, "clipboard", false, "Copy the formatted output to the clipboard instead of saving it to a file")
+	flag.BoolVar(&opts.RespectRobots, "respect-robots", false, "Skip URLs disallowed by robots.txt when crawling and honor its Crawl-delay")
+	flag.BoolVar(&opts.ThirdPartyScripts, "third-party-scripts", false, "Count the scripts loaded from hosts other than the page's")
+	flag.StringVar(&opts.DownloadImages, "download-images", "", "Directory to download the page images into, resuming partial downloads")
+	var allowDomains stringList
+	flag.Var(&allowDomains, "allow-domains", "Comma-separated domains, besides the start host, the crawl may follow links to (repeatable)")
+	var excludes stringList
+	flag.Var(&excludes, "exclude-selector", "Remove elements matching this CSS selector before extracting (repeatable)")
+	fieldMapFlag := flag.String("field-map", "", "Rename and order JSON output keys, e.g. links->urls,texts->paragraphs")
+	var jsonPaths stringList
+	flag.Var(&jsonPaths, "json-path", "Treat the response as JSON and extract the values at this path, e.g. items.#.name (repeatable)")
+	acceptStatuses := flag.String("accept-status", "", "Status codes to parse pages for besides 200, as a list of codes and ranges (e.g. 301-302,404)")
+	merge := flag.Bool("merge", false, "Merge the JSON result files given as arguments into one, deduplicating links, texts and images")
+	retryBody := flag.String("retry-if-body-matches", "", "Retry 200 responses whose body matches this regexp, e.g. captcha pages")
+	var hostDepths stringList
+	flag.Var(&hostDepths, "host-depth", "Crawl depth limit for one host, host=N (repeatable)")
+	flag.BoolVar(&opts.Tables, "tables", false, "Extract tables with their captions, cell grid and rows keyed by header")
+	flag.StringVar(&opts.TokensFile, "tokens-file", "", "File of bearer tokens, one per line, to rotate through per request")
+	flag.BoolVar(&opts.Times, "times", false, "Extract <time> elements with their datetime and parsed time")
+	flag.BoolVar(&opts.Headings, "headings", false, "Extract headings with their id, or a slug of their text, for deep links")
+	flag.IntVar(&opts.FlushEvery, "flush-every", 0, "Append crawl results to the output file every N pages and drop them from memory (text and jsonl formats)")
+	flag.BoolVar(&opts.OnlyErrors, "only-errors", false, "Only report the URLs that failed, exiting with status 1 if any did")
+	flag.BoolVar(&opts.BgImages, "bg-images", false, "Collect background-image URLs from style attributes and <style> blocks")
+	flag.BoolVar(&opts.FollowCanonical, "follow-canonical", false, "Scrape the page's <link rel=\"canonical\"> URL instead when it is another page on the same host; crawls queue it in the variant's place")
+	flag.BoolVar(&opts.FollowMetaRefresh, "follow-meta-refresh", false, "Follow <meta http-equiv=\"refresh\"> redirects to their target page")
+	flag.IntVar(&opts.DownloadConcurrency, "download-concurrency", 4, "Maximum simultaneous image downloads with -download-images")
+	flag.IntVar(&opts.LinkContext, "link-context", 0, "Keep up to N characters of the text around each link")
+	flag.BoolVar(&opts.UseCache, "use-cache", false, "Cache responses on disk and serve repeated requests from the cache")
+	flag.StringVar(&opts.CacheDir, "cache-dir", ".scrape-cache", "Directory for the -use-cache response cache")
+	flag.DurationVar(&opts.CacheTTL, "cache-ttl", 0, "Refetch cached responses older than this (0 to keep them forever)")
+	flag.StringVar(&opts.LogRequests, "log-requests", "", "Log the method, URL and headers of every request to this file")
+	flag.BoolVar(&opts.LogSecrets, "log-secrets", false, "Don't redact Authorization, Cookie and similar headers in the -log-requests file")
+	flag.StringVar(&opts.ParseDates, "parse-dates", "", "Normalize the dates and numbers in the text of elements matching this selector to ISO dates and plain numbers")
+	flag.StringVar(&opts.Locale, "locale", "", "Locale of -parse-dates text, e.g. en-US or de-DE, deciding numeric date order and the decimal separator")
+	flag.StringVar(&opts.ParsePrices, "parse-prices", "", "Parse the text of elements matching this selector as prices with their currency")
+	flag.StringVar(&opts.SeenFile, "seen-file", "", "Only output links not recorded in this file by earlier runs, then add them to it")
+	flag.BoolVar(&opts.FAQ, "faq", false, "Extract FAQ questions and answers from FAQPage JSON-LD, <dl> lists or <details>")
+	flag.IntVar(&opts.Retries, "retries", 2, "Times to retry a page whose body was cut short or matched -retry-if-body-matches")
+	flag.IntVar(&opts.MaxTotalRetries, "max-total-retries", 0, "Maximum retries across the whole crawl; once used up, failures are not retried (0 for no limit)")
+	flag.BoolVar(&opts.Profile, "profile", false, "Time the fetch, HTML parsing and each extractor of every page and report the breakdown")
+	flag.BoolVar(&opts.DOMMetrics, "dom-metrics", false, "Report element counts per tag, text nodes and maximum DOM depth")
+	flag.BoolVar(&opts.CheckConsistency, "check-consistency", false, "Report when <title>, OpenGraph, Twitter and JSON-LD titles, URLs or descriptions disagree")
+	flag.StringVar(&opts.HAR, "har", "", "Scrape the responses captured in this HAR file offline; without -url every HTML response in it is scraped")
+	flag.StringVar(&opts.Strict, "strict", "", "Check for duplicate IDs and malformed attributes: warn adds them to the warnings, fail also fails the page")
+	flag.StringVar(&esIndex, "index", esIndex, "Index named in the action lines of -format es-bulk")
+	flag.StringVar(&opts.ClientCert, "client-cert", "", "PEM client certificate for mutual TLS (needs -client-key)")
+	flag.StringVar(&opts.ClientKey, "client-key", "", "PEM private key for -client-cert")
+	flag.BoolVar(&opts.PageWeight, "page-weight", false, "Total the size of images, scripts and stylesheets with HEAD requests")
+	flag.Float64Var(&opts.SampleRate, "sample-rate", 1, "Fraction (0.0-1.0) of crawled pages to scrape; the others are only used to discover links")
+	flag.Int64Var(&opts.Seed, "seed", 0, "Seed for -sample-rate and -crawl-order random so crawls can be reproduced (0 picks and logs a random seed)")
+	flag.StringVar(&opts.CrawlOrder, "crawl-order", "bfs", "Order to crawl queued pages in: bfs (breadth-first), dfs (depth-first) or random")
+	flag.BoolVar(&opts.Breadcrumbs, "breadcrumbs", false, "Extract breadcrumb trails and build a site-structure tree from them across a crawl; -format dot then draws the tree")
+	flag.BoolVar(&opts.Event, "event", false, "Extract events (name, start and end dates, location, price) from Event JSON-LD or microdata")
+	flag.BoolVar(&opts.Video, "video", false, "Extract video metadata (name, duration, thumbnail, URLs) from VideoObject JSON-LD or og:video tags")
+	flag.BoolVar(&opts.Rating, "rating", false, "Extract the aggregate rating (value, review count, best rating) from JSON-LD, microdata or star widgets")
+	flag.StringVar(&opts.Sign, "sign", "", "Sign each request: aws-sigv4 (credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN)")
+	flag.StringVar(&opts.SignService, "sign-service", "", "AWS service name for -sign aws-sigv4 (e.g. execute-api, s3)")
+	flag.StringVar(&opts.SignRegion, "sign-region", "", "AWS region for -sign aws-sigv4 (default $AWS_REGION)")
+	flag.BoolVar(&opts.StripTracking, "strip-tracking", false, "Remove tracking query parameters (utm_*, fbclid, gclid, mc_eid, ...) from links, keeping the others")
+	flag.BoolVar(&opts.IncludeTemplates, "include-templates", false, "Keep the markup of <template> and <noscript> elements, and extract links, texts and images from <noscript>")
+	flag.BoolVar(&opts.Viewport, "viewport", false, "Parse <meta name=\"viewport\"> and warn about pages without one")
 	flag.Parse()
 
-	if *url == "" {
+	if *url == "" && !opts.Resume && *jobsFile == "" && opts.HAR == "" && !*merge {
 		log.Fatal("Please provide a URL using the -url flag")
 	}
+	if !validFormat(*format) {
+		log.Fatalf("Unknown -format %q", *format)
+	}
+	opts.LinkGraph = *format == "dot" && !opts.Breadcrumbs
+	opts.ESDocuments = *format == "es-bulk"
+	opts.ParquetRows = *format == "parquet"
+	for _, h := range headers {
+		name, value, err := parseHeader(h)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if opts.Headers == nil {
+			opts.Headers = make(map[string]string)
+		}
+		opts.Headers[name] = value
+	}
+	for _, r := range resolves {
+		host, ip, err := parseResolve(r)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if opts.Resolve == nil {
+			opts.Resolve = make(map[string]string)
+		}
+		opts.Resolve[host] = ip
+	}
+	opts.JSONPaths = jsonPaths
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		log.Fatalf("Invalid -sample-rate %g, want 0.0-1.0", opts.SampleRate)
+	}
+	switch opts.Strict {
+	case "", "warn", "fail":
+	default:
+		log.Fatalf("Invalid -strict %q, want warn or fail", opts.Strict)
+	}
+	if *acceptStatuses != "" {
+		ranges, err := parseStatusRanges(*acceptStatuses)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.AcceptStatus = ranges
+	}
+	if *retryBody != "" {
+		re, err := regexp.Compile(*retryBody)
+		if err != nil {
+			log.Fatalf("Invalid -retry-if-body-matches: %v", err)
+		}
+		opts.RetryBodyMatch = re
+	}
+	if *fieldMapFlag != "" {
+		m, err := parseFieldMap(*fieldMapFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		outputFields = m
+	}
+	for _, s := range excludes {
+		if s = strings.TrimSpace(s); s != "" {
+			opts.ExcludeSelectors = append(opts.ExcludeSelectors, s)
+		}
+	}
+	for _, list := range allowDomains {
+		for _, domain := range strings.Split(list, ",") {
+			if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+				opts.AllowDomains = append(opts.AllowDomains, domain)
+			}
+		}
+	}
+	for _, h := range hostDepths {
+		host, depth, err := parseHostDepth(h)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if opts.HostDepth == nil {
+			opts.HostDepth = make(map[string]int)
+		}
+		opts.HostDepth[host] = depth
+	}
+	if err := setupClient(opts); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := parseLocale(opts.Locale); err != nil {
+		log.Fatal(err)
+	}
+	for _, s := range selects {
+		rule, err := parseSelectRule(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Selects = append(opts.Selects, rule)
+	}
+
+	// Cancel on Ctrl+C or SIGTERM; a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if *jobsFile != "" {
+		results, err := runJobs(ctx, *jobsFile, opts)
+		if err != nil {
+			log.Fatalf("Failed to run jobs: %v", err)
+		}
+		failures := jobFailures(results)
+		switch {
+		case opts.Quiet:
+		case opts.OnlyErrors:
+			writeErrors(os.Stdout, failures)
+		default:
+			writeJobResults(os.Stdout, results, "text")
+		}
+		if shouldSave(opts) {
+			if err := saveJobResults(results, *output, *format); err != nil {
+				log.Printf("Error saving to file: %v", err)
+			} else {
+				opts.printf("Data saved to %s\n", *output)
+			}
+		}
+		if opts.OnlyErrors && len(failures) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if opts.Repeat > 0 {
+		runBenchmark(ctx, *url, opts)
+		return
+	}
+
+	if opts.Stream {
+		if err := streamToFile(ctx, *url, opts, *output, *format); err != nil {
+			log.Fatalf("Failed to scrape: %v", err)
+		}
+		if *output != "-" {
+			opts.printf("Data saved to %s\n", *output)
+		}
+		return
+	}
 
-	// Scrape the page
-	data, err := scrapePage(*url)
+	if opts.FlushEvery > 0 && (opts.Depth > 0 || opts.Resume) {
+		failures, err := crawlToFile(ctx, *url, opts, *output, *format)
+		if err != nil && !errors.Is(err, errInterrupted) {
+			log.Fatalf("Failed to crawl: %v", err)
+		}
+		if opts.OnlyErrors && !opts.Quiet {
+			writeErrors(os.Stdout, failures)
+		}
+		opts.printf("Data saved to %s\n", *output)
+		if opts.OnlyErrors && len(failures) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Scrape the page, or crawl from it
+	var data ScrapeData
+	var err error
+	switch {
+	case *merge:
+		if flag.NArg() == 0 {
+			log.Fatal("-merge needs the JSON result files to merge as arguments")
+		}
+		data, err = mergeResults(flag.Args())
+	case opts.Depth > 0 || opts.Resume:
+		data, err = crawl(ctx, *url, opts)
+	case opts.PageParam != "":
+		data, err = paginate(ctx, *url, opts)
+	case *url == "" && opts.HAR != "":
+		data, err = scrapeHAR(ctx, opts)
+	default:
+		data, err = scrapePage(ctx, *url, opts)
+	}
+	if errors.Is(err, errInterrupted) {
+		// Save what was gathered without prompting
+		data.ContentHash = contentHash(data)
+		if err := saveToFile(data, *output, *format); err != nil {
+			log.Fatalf("Error saving to file: %v", err)
+		}
+		opts.printf("Partial data saved to %s\n", *output)
+		return
+	}
 	if err != nil {
 		log.Fatalf("Failed to scrape: %v", err)
 	}
+	if opts.SeenFile != "" {
+		seen, err := loadSeen(opts.SeenFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		total := len(data.Links)
+		fresh := keepNewLinks(&data, seen)
+		if err := saveSeen(opts.SeenFile, seen); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%d of %d links are new", fresh, total)
+	}
+	data.ContentHash = contentHash(data)
+
+	if *diffFile != "" {
+		prev, err := loadResult(*diffFile)
+		if err != nil {
+			log.Fatalf("Failed to load previous result: %v", err)
+		}
+		writeDiff(os.Stdout, prev, data)
+		fmt.Println()
+	}
 
 	// Print results
-	fmt.Println("Scraped Links:")
-	for i, link := range data.Links {
-		fmt.Printf("%d. %s\n", i+1, link)
+	switch {
+	case opts.Quiet:
+	case opts.OnlyErrors:
+		writeErrors(os.Stdout, data.Errors)
+	case opts.CountOnly:
+		writeCounts(os.Stdout, data)
+	default:
+		writeText(os.Stdout, data)
 	}
 
-	fmt.Println("\nScraped Text (Paragraphs):")
-	for i, text := range data.Texts {
-		fmt.Printf("%d. %s\n", i+1, text)
+	if opts.Clipboard {
+		if err := copyToClipboard(data, *format); err != nil {
+			log.Fatalf("Error copying output: %v", err)
+		}
+		opts.printf("Data copied to clipboard\n")
+	} else if shouldSave(opts) {
+		if err := saveToFile(data, *output, *format); err != nil {
+			log.Printf("Error saving to file: %v", err)
+		} else {
+			opts.printf("Data saved to %s\n", *output)
+		}
 	}
+	if opts.OnlyErrors && len(data.Errors) > 0 {
+		os.Exit(1)
+	}
+}
 
-	fmt.Println("\nScraped Images:")
-	for i, src := range data.Images {
-		fmt.Printf("%d. %s\n", i+1, src)
+// shouldSave reports whether the data should be written to the output file:
+// always with -quiet, otherwise only if the user confirms.
+func shouldSave(opts Options) bool {
+	return opts.Quiet || confirmSave()
+}
+
+// printf prints a progress message to stdout unless -quiet is set.
+func (o Options) printf(format string, args ...any) {
+	if !o.Quiet {
+		fmt.Printf(format, args...)
 	}
+}
 
-	// Ask user if they want to save the data
+// confirmSave asks the user whether the scraped data should be saved.
+func confirmSave() bool {
 	fmt.Print("\nWould you like to save the scraped data to a file? (y/n): ")
 	reader := bufio.NewReader(os.Stdin)
 	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response == "y" {
-		if err := saveToFile(data, *output); err != nil {
-			log.Printf("Error saving to file: %v", err)
-		} else {
-			fmt.Printf("Data saved to %s\n", *output)
-		}
-	}
+	return strings.TrimSpace(strings.ToLower(response)) == "y"
 }
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer serves testdata/page.html at /page along with redirects,
+// error statuses, soft failures and a slow page.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	page, err := os.ReadFile("testdata/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	})
+	var flakyCalls atomic.Int32
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		// The first response drops the connection halfway through the body
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if flakyCalls.Add(1) == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+			w.Write(page[:len(page)/2])
+			return
+		}
+		w.Write(page)
+	})
+	var captchaCalls atomic.Int32
+	mux.HandleFunc("/captcha", func(w http.ResponseWriter, r *http.Request) {
+		// The first response is a soft failure served with 200 OK
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if captchaCalls.Add(1) == 1 {
+			w.Write([]byte("<html><body><p>Please solve the captcha</p></body></html>"))
+			return
+		}
+		w.Write(page)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// checkFixture asserts the data extracted from testdata/page.html.
+func checkFixture(t *testing.T, data ScrapeData) {
+	t.Helper()
+	wantLinks := []Link{
+		{URL: "https://example.com/one", Rel: []string{}},
+		{URL: "https://example.com/two", Rel: []string{"nofollow", "noopener"}},
+	}
+	if !reflect.DeepEqual(data.Links, wantLinks) {
+		t.Errorf("Links = %+v, want %+v", data.Links, wantLinks)
+	}
+	wantTexts := []TextEntry{
+		{Text: "First paragraph.", Lang: "en", WordCount: 2},
+		{Text: "Deuxième paragraphe.", Lang: "fr", WordCount: 2},
+	}
+	if !reflect.DeepEqual(data.Texts, wantTexts) {
+		t.Errorf("Texts = %+v, want %+v", data.Texts, wantTexts)
+	}
+	wantImages := []string{"/logo.png", "https://cdn.example.com/photo.jpg"}
+	if !reflect.DeepEqual(data.Images, wantImages) {
+		t.Errorf("Images = %v, want %v", data.Images, wantImages)
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	f, err := os.Open("testdata/page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	base, _ := url.Parse("https://example.com/page")
+
+	data, err := parsePage(f, base, Options{})
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	checkFixture(t, data)
+}
+
+func TestParsePageOptions(t *testing.T) {
+	html := `<html><body><div id="main"><p>Hello <b>there</b></p></div></body></html>`
+	data, err := parsePage(strings.NewReader(html), nil, Options{WithPath: true, WithHTML: true})
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	want := []TextEntry{{Text: "Hello there", Path: "body > div#main > p", HTML: "Hello <b>there</b>", WordCount: 2}}
+	if !reflect.DeepEqual(data.Texts, want) {
+		t.Errorf("Texts = %+v, want %+v", data.Texts, want)
+	}
+}
+
+func TestParsePageExcludeSelector(t *testing.T) {
+	html := `<html><body><nav><p>Menu</p><a href="https://example.com/nav">Nav</a></nav>
+<p>Body</p><footer><p>Footer</p></footer></body></html>`
+	data, err := parsePage(strings.NewReader(html), nil, Options{ExcludeSelectors: []string{"nav", "footer"}})
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	if want := []TextEntry{{Text: "Body", WordCount: 1}}; !reflect.DeepEqual(data.Texts, want) {
+		t.Errorf("Texts = %+v, want %+v", data.Texts, want)
+	}
+	if len(data.Links) != 0 {
+		t.Errorf("Links = %+v, want none", data.Links)
+	}
+}
+
+func TestParsePageDataURIImages(t *testing.T) {
+	html := `<html><body><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">
+<img src="data:text/plain,hi"><img src="https://example.com/a.png"></body></html>`
+	data, err := parsePage(strings.NewReader(html), nil, Options{})
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	if want := []string{"https://example.com/a.png"}; !reflect.DeepEqual(data.Images, want) {
+		t.Errorf("Images = %v, want %v", data.Images, want)
+	}
+	if len(data.InlineImages) != 1 || data.InlineImages[0].MIME != "image/gif" || data.InlineImages[0].Size != 14 {
+		t.Errorf("InlineImages = %+v, want one 14-byte image/gif", data.InlineImages)
+	}
+}
+
+func TestParsePageNoscriptImages(t *testing.T) {
+	html := `<html><body><noscript><img src="https://example.com/a.png">
+<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw="></noscript></body></html>`
+	data, err := parsePage(strings.NewReader(html), nil, Options{IncludeTemplates: true})
+	if err != nil {
+		t.Fatalf("parsePage: %v", err)
+	}
+	if want := []string{"https://example.com/a.png"}; !reflect.DeepEqual(data.Images, want) {
+		t.Errorf("Images = %v, want %v", data.Images, want)
+	}
+	if len(data.InlineImages) != 1 || data.InlineImages[0].MIME != "image/gif" {
+		t.Errorf("InlineImages = %+v, want the image/gif from <noscript>", data.InlineImages)
+	}
+}
+
+func TestParsePageReadError(t *testing.T) {
+	_, err := parsePage(errReader{}, nil, Options{})
+	if err == nil {
+		t.Fatal("parsePage succeeded on a failing reader")
+	}
+}
+
+// errReader fails every read.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestScrapePage(t *testing.T) {
+	srv := newTestServer(t)
+	data, err := scrapePage(context.Background(), srv.URL+"/page", Options{})
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	checkFixture(t, data)
+	if data.URL != srv.URL+"/page" {
+		t.Errorf("URL = %q, want %q", data.URL, srv.URL+"/page")
+	}
+	if data.ScrapedAt.IsZero() {
+		t.Error("ScrapedAt not set")
+	}
+}
+
+func TestScrapePageRedirect(t *testing.T) {
+	srv := newTestServer(t)
+	data, err := scrapePage(context.Background(), srv.URL+"/redirect", Options{})
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	// The data belongs to the page the redirect ended at
+	if data.URL != srv.URL+"/page" {
+		t.Errorf("URL = %q, want %q", data.URL, srv.URL+"/page")
+	}
+	checkFixture(t, data)
+}
+
+func TestScrapePageStatus(t *testing.T) {
+	srv := newTestServer(t)
+	for path, code := range map[string]int{"/missing": 404, "/broken": 500} {
+		_, err := scrapePage(context.Background(), srv.URL+path, Options{})
+		var se *statusError
+		if !errors.As(err, &se) {
+			t.Errorf("%s: err = %v, want a statusError", path, err)
+			continue
+		}
+		if se.Code != code {
+			t.Errorf("%s: status = %d, want %d", path, se.Code, code)
+		}
+	}
+}
+
+func TestScrapePageAcceptStatus(t *testing.T) {
+	srv := newTestServer(t)
+	opts := Options{AcceptStatus: []statusRange{{404, 404}}}
+	data, err := scrapePage(context.Background(), srv.URL+"/page", opts)
+	if err != nil {
+		t.Fatalf("scrapePage with -accept-status 404 on a 200 page: %v", err)
+	}
+	checkFixture(t, data)
+	if _, err := scrapePage(context.Background(), srv.URL+"/missing", opts); err != nil {
+		t.Errorf("scrapePage on an accepted 404: %v", err)
+	}
+	if _, err := scrapePage(context.Background(), srv.URL+"/broken", opts); err == nil {
+		t.Error("scrapePage accepted a 500 not listed in -accept-status")
+	}
+}
+
+func TestScrapePageTimeout(t *testing.T) {
+	srv := newTestServer(t)
+	start := time.Now()
+	_, err := scrapePage(context.Background(), srv.URL+"/slow", Options{PageTimeout: 100 * time.Millisecond})
+	if err == nil {
+		t.Fatal("scrapePage succeeded on a page slower than -page-timeout")
+	}
+	if !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("err = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("scrapePage took %s, want it cut off at the timeout", elapsed)
+	}
+}
+
+func TestScrapePageTruncated(t *testing.T) {
+	srv := newTestServer(t)
+	data, err := scrapePage(context.Background(), srv.URL+"/flaky", Options{})
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	if !data.Truncated || len(data.Warnings) != 1 {
+		t.Errorf("Truncated = %v, Warnings = %q, want a truncated page with a warning", data.Truncated, data.Warnings)
+	}
+}
+
+func TestScrapePageTruncatedCache(t *testing.T) {
+	srv := newTestServer(t)
+	dir := t.TempDir()
+	data, err := scrapePage(context.Background(), srv.URL+"/flaky", Options{UseCache: true, CacheDir: dir})
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	if !data.Truncated || len(data.Warnings) != 1 {
+		t.Errorf("Truncated = %v, Warnings = %q, want a truncated page with a warning", data.Truncated, data.Warnings)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cache holds %d entries, want the partial body left out", len(entries))
+	}
+}
+
+func TestScrapePageTruncatedRetryBodyMatch(t *testing.T) {
+	srv := newTestServer(t)
+	opts := Options{Retries: 1, RetryBodyMatch: regexp.MustCompile("captcha")}
+	data, err := scrapePage(context.Background(), srv.URL+"/flaky", opts)
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	if data.Truncated {
+		t.Errorf("Truncated = true after a successful retry")
+	}
+	checkFixture(t, data)
+}
+
+func TestScrapePageTruncatedRetry(t *testing.T) {
+	srv := newTestServer(t)
+	data, err := scrapePage(context.Background(), srv.URL+"/flaky", Options{Retries: 1})
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	if data.Truncated {
+		t.Errorf("Truncated = true after a successful retry")
+	}
+	checkFixture(t, data)
+}
+
+func TestScrapePageRetryBodyMatchCache(t *testing.T) {
+	srv := newTestServer(t)
+	opts := Options{
+		Retries:        1,
+		RetryBodyMatch: regexp.MustCompile("captcha"),
+		UseCache:       true,
+		CacheDir:       t.TempDir(),
+	}
+	data, err := scrapePage(context.Background(), srv.URL+"/captcha", opts)
+	if err != nil {
+		t.Fatalf("scrapePage: %v", err)
+	}
+	// The retry is not served the cached soft failure
+	checkFixture(t, data)
+}
+
+func TestScrapePageCancelled(t *testing.T) {
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := scrapePage(ctx, srv.URL+"/page", Options{}); err == nil {
+		t.Fatal("scrapePage succeeded with a cancelled context")
+	}
+}
+
+func TestScrapePageUnreachable(t *testing.T) {
+	srv := newTestServer(t)
+	addr := srv.URL
+	srv.Close()
+	if _, err := scrapePage(context.Background(), addr+"/page", Options{}); err == nil {
+		t.Fatal("scrapePage succeeded against a closed server")
+	}
+}
+
+func TestScrapePageInvalidURL(t *testing.T) {
+	if _, err := scrapePage(context.Background(), "http://[::1", Options{}); err == nil {
+		t.Fatal("scrapePage succeeded with an invalid URL")
+	}
+}
+
+func TestCrawlFollowCanonical(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	page := func(canonical, text string, links ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><head>")
+			if canonical != "" {
+				fmt.Fprintf(w, `<link rel="canonical" href="%s%s">`, srv.URL, canonical)
+			}
+			fmt.Fprintf(w, "</head><body><p>%s</p>", text)
+			for _, link := range links {
+				fmt.Fprintf(w, `<a href="%s%s">%s</a>`, srv.URL, link, link)
+			}
+			fmt.Fprint(w, "</body></html>")
+		}
+	}
+	mux.HandleFunc("/start", page("", "Start", "/variant", "/x"))
+	// A variant of a page already crawled is dropped
+	mux.HandleFunc("/variant", page("/start", "Variant"))
+	// Pages naming each other as canonical: one of them must be kept
+	mux.HandleFunc("/x", page("/y", "X"))
+	mux.HandleFunc("/y", page("/x", "Y"))
+
+	data, err := crawl(context.Background(), srv.URL+"/start", Options{Depth: 2, FollowCanonical: true, SampleRate: 1})
+	if err != nil {
+		t.Fatalf("crawl: %v", err)
+	}
+	var texts []string
+	for _, text := range data.Texts {
+		texts = append(texts, text.Text)
+	}
+	if want := []string{"Start", "Y"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("Texts = %q, want %q", texts, want)
+	}
+	if len(data.Errors) != 0 {
+		t.Errorf("Errors = %+v, want none", data.Errors)
+	}
+}
+
+func TestParseSelectRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want selectRule
+	}{
+		{"title=h1", selectRule{Name: "title", Selector: "h1"}},
+		{"links=a.more@href", selectRule{Name: "links", Selector: "a.more", Attr: "href"}},
+		{`mail=a[href^="mailto:x@y"]`, selectRule{Name: "mail", Selector: `a[href^="mailto:x@y"]`}},
+		{`mail=a[href^="mailto:x@y"]@href`, selectRule{Name: "mail", Selector: `a[href^="mailto:x@y"]`, Attr: "href"}},
+		{`at=a:contains("@") @title`, selectRule{Name: "at", Selector: `a:contains("@")`, Attr: "title"}},
+	}
+	for _, tt := range tests {
+		got, err := parseSelectRule(tt.in)
+		if err != nil {
+			t.Errorf("parseSelectRule(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSelectRule(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlMethodStartOnly(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	var methods sync.Map
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		methods.Store(r.URL.Path, r.Method)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><body><p>%s</p><a href="%s/next">Next</a></body></html>`, r.URL.Path, srv.URL)
+	})
+
+	opts := Options{Depth: 1, SampleRate: 1, Method: "POST", Data: "q=go"}
+	if _, err := crawl(context.Background(), srv.URL+"/start", opts); err != nil {
+		t.Fatalf("crawl: %v", err)
+	}
+	for path, want := range map[string]string{"/start": "POST", "/next": "GET"} {
+		if got, _ := methods.Load(path); got != want {
+			t.Errorf("%s fetched with %v, want %s", path, got, want)
+		}
+	}
+}
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		num     string
+		decimal byte
+		want    string
+	}{
+		{"1.234,56", 0, "1234.56"},
+		{"1,234.56", 0, "1234.56"},
+		{"1.234", 0, "1234"},
+		{"1,234", 0, "1234"},
+		{"12,5", 0, "12.5"},
+		{"12.50", 0, "12.50"},
+		{"1.234.567", 0, "1234567"},
+		{"-3,5", 0, "-3.5"},
+		{"1.234", ',', "1234"},
+		{"1.234", '.', "1.234"},
+		{"1,234", ',', "1.234"},
+	}
+	for _, tt := range tests {
+		if got := normalizeNumber(tt.num, tt.decimal); got != tt.want {
+			t.Errorf("normalizeNumber(%q, %q) = %q, want %q", tt.num, tt.decimal, got, tt.want)
+		}
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in       string
+		amount   float64
+		currency string
+	}{
+		{"1.234,56 €", 1234.56, "EUR"},
+		{"$1,234.56", 1234.56, "USD"},
+		{"CHF 12.50", 12.5, "CHF"},
+		{"12,5 zł", 12.5, "PLN"},
+		{"US$ 1.234", 1234, "USD"},
+		{"NOW 19.99", 19.99, ""},
+		{"NEW $5", 5, "USD"},
+	}
+	for _, tt := range tests {
+		p := parsePrice(tt.in)
+		if p.Amount == nil || *p.Amount != tt.amount || p.Currency != tt.currency {
+			t.Errorf("parsePrice(%q) = %+v, want %v %s", tt.in, p, tt.amount, tt.currency)
+		}
+	}
+}
+
+func TestParseLocalizedDates(t *testing.T) {
+	tests := []struct {
+		text   string
+		locale string
+		want   string
+	}{
+		{"2024-03-05", "", "2024-03-05"},
+		{"5 March 2024", "", "2024-03-05"},
+		{"March 5th, 2024", "en-US", "2024-03-05"},
+		{"5. März 2024", "de-DE", "2024-03-05"},
+		{"5 de marzo de 2024", "es-ES", "2024-03-05"},
+		{"1er janvier 2024", "fr-FR", "2024-01-01"},
+		{"05/03/2024", "de-DE", "2024-03-05"},
+		{"03/05/2024", "en-US", "2024-03-05"},
+		{"25/12/24", "en-US", "2024-12-25"},
+		{"2024.03.05", "", "2024-03-05"},
+		{"05.03.99", "de-DE", "1999-03-05"},
+	}
+	for _, tt := range tests {
+		hint, err := parseLocale(tt.locale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := parseLocalized(tt.text, hint)
+		if len(got) != 1 || got[0].Kind != "date" || got[0].Value != tt.want {
+			t.Errorf("parseLocalized(%q, %q) = %+v, want the date %s", tt.text, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestParseLocalizedOrder(t *testing.T) {
+	hint, _ := parseLocale("de-DE")
+	got := parseLocalized("Ab 1.234,50 EUR bis 31.12.2024, dann 99 Stück", hint)
+	want := []LocalizedValue{
+		{Raw: "1.234,50", Kind: "number", Value: "1234.5"},
+		{Raw: "31.12.2024", Kind: "date", Value: "2024-12-31"},
+		{Raw: "99", Kind: "number", Value: "99"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLocalized = %+v, want %+v", got, want)
+	}
+	// Invalid dates and unknown month names are not dates
+	for _, text := range []string{"31.02.2024", "5 Foo 2024"} {
+		for _, v := range parseLocalized(text, hint) {
+			if v.Kind == "date" {
+				t.Errorf("parseLocalized(%q) found the date %s", text, v.Value)
+			}
+		}
+	}
+}
+
+// TestSigV4GetVanilla checks the signer against the get-vanilla case of
+// AWS's Signature Version 4 test suite.
+func TestSigV4GetVanilla(t *testing.T) {
+	s := &sigV4Signer{
+		accessKey: "AKIDEXAMPLE",
+		secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		service:   "service",
+		region:    "us-east-1",
+		now:       func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sign(req); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q\nwant %q", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q, want 20150830T123600Z", got)
+	}
+}
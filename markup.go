@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// checkDuplicateIDs returns a warning for every id used by more than one
+// element; selectors and fragment links only see the first of them.
+func checkDuplicateIDs(doc *goquery.Document) []string {
+	counts := make(map[string]int)
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id, _ := s.Attr("id")
+		counts[id]++
+	})
+	var ids []string
+	for id, n := range counts {
+		if n > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	warnings := make([]string, len(ids))
+	for i, id := range ids {
+		warnings[i] = fmt.Sprintf("duplicate id %q on %d elements", id, counts[id])
+	}
+	return warnings
+}
+
+// checkAttributes tokenizes raw HTML and returns a warning for each start
+// tag with a malformed attribute, which parsers recover from in different
+// ways: a name containing quotes, < or =, a duplicated name, or an unquoted
+// value containing characters that are not allowed there.
+func checkAttributes(raw []byte) []string {
+	var warnings []string
+	z := html.NewTokenizer(bytes.NewReader(raw))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return warnings
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tag := string(z.Raw())
+		name, _ := z.TagName()
+		seen := make(map[string]bool)
+		for _, attr := range scanAttributes(tag) {
+			switch {
+			case strings.ContainsAny(attr.name, "\"'<="):
+				warnings = append(warnings, fmt.Sprintf("malformed attribute name %q in <%s>", attr.name, name))
+			case seen[attr.name]:
+				warnings = append(warnings, fmt.Sprintf("duplicate attribute %q in <%s>", attr.name, name))
+			case attr.unquoted && strings.ContainsAny(attr.value, "\"'<=`"):
+				warnings = append(warnings, fmt.Sprintf("unquoted attribute %s=%s in <%s>", attr.name, attr.value, name))
+			}
+			seen[attr.name] = true
+		}
+	}
+}
+
+// rawAttr is an attribute as written in the source of a tag.
+type rawAttr struct {
+	name, value string
+	unquoted    bool
+}
+
+// scanAttributes splits the source of a start tag into its attributes,
+// following the HTML tokenizer's rules closely enough to tell quoted values
+// from unquoted ones.
+func scanAttributes(tag string) []rawAttr {
+	tag = strings.TrimPrefix(tag, "<")
+	tag = strings.TrimSuffix(tag, ">")
+	i := strings.IndexAny(tag, " \t\n\f\r/")
+	if i < 0 {
+		return nil
+	}
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == '/' }
+
+	var attrs []rawAttr
+	for i < len(tag) {
+		for i < len(tag) && isSpace(tag[i]) {
+			i++
+		}
+		start := i
+		// A leading = belongs to the name
+		if i < len(tag) && tag[i] == '=' {
+			i++
+		}
+		for i < len(tag) && !isSpace(tag[i]) && tag[i] != '=' {
+			i++
+		}
+		if start == i {
+			break
+		}
+		attr := rawAttr{name: strings.ToLower(tag[start:i])}
+		j := i
+		for j < len(tag) && isSpace(tag[j]) && tag[j] != '/' {
+			j++
+		}
+		if j < len(tag) && tag[j] == '=' {
+			i = j + 1
+			for i < len(tag) && isSpace(tag[i]) && tag[i] != '/' {
+				i++
+			}
+			if i < len(tag) && (tag[i] == '"' || tag[i] == '\'') {
+				end := strings.IndexByte(tag[i+1:], tag[i])
+				if end < 0 {
+					end = len(tag) - i - 1
+				}
+				attr.value = tag[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(tag) && !strings.ContainsRune(" \t\n\f\r", rune(tag[i])) {
+					i++
+				}
+				attr.value, attr.unquoted = tag[start:i], true
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
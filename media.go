@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractMedia collects the absolute URLs of <video> and <audio> sources,
+// including their <source> children, and video poster images. The result is
+// keyed by "video", "audio" and "poster".
+func extractMedia(doc *goquery.Document, base *url.URL) map[string][]string {
+	result := make(map[string][]string)
+	seen := make(map[string]bool)
+	add := func(kind, ref string) {
+		abs, ok := resolveURL(base, ref)
+		if !ok || seen[kind+" "+abs] {
+			return
+		}
+		seen[kind+" "+abs] = true
+		result[kind] = append(result[kind], abs)
+	}
+
+	for _, kind := range []string{"video", "audio"} {
+		doc.Find(kind).Each(func(i int, s *goquery.Selection) {
+			if src, ok := s.Attr("src"); ok {
+				add(kind, src)
+			}
+			s.Find("source[src]").Each(func(i int, source *goquery.Selection) {
+				src, _ := source.Attr("src")
+				add(kind, src)
+			})
+			if poster, ok := s.Attr("poster"); ok {
+				add("poster", poster)
+			}
+		})
+	}
+	return result
+}
+
+// Patterns for background images in CSS: a background or background-image
+// declaration, and each url() in its value.
+var (
+	cssBackground = regexp.MustCompile(`(?i)background(?:-image)?\s*:([^;}]*)`)
+	cssURL        = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"]*))\s*\)`)
+)
+
+// extractBackgroundImages collects the absolute URLs of background images
+// set in inline style attributes and <style> blocks.
+func extractBackgroundImages(doc *goquery.Document, base *url.URL) []string {
+	var images []string
+	seen := make(map[string]bool)
+	scan := func(css string) {
+		for _, decl := range cssBackground.FindAllStringSubmatch(css, -1) {
+			for _, m := range cssURL.FindAllStringSubmatch(decl[1], -1) {
+				ref := strings.TrimSpace(m[1] + m[2] + m[3])
+				if strings.HasPrefix(strings.ToLower(ref), "data:") {
+					continue
+				}
+				if abs, ok := resolveURL(base, ref); ok && !seen[abs] {
+					seen[abs] = true
+					images = append(images, abs)
+				}
+			}
+		}
+	}
+	doc.Find("[style]").Each(func(i int, s *goquery.Selection) {
+		style, _ := s.Attr("style")
+		scan(style)
+	})
+	doc.Find("style").Each(func(i int, s *goquery.Selection) {
+		scan(s.Text())
+	})
+	return images
+}
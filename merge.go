@@ -0,0 +1,98 @@
+package main
+
+import "time"
+
+// MergeSource is one of the result files combined with -merge.
+type MergeSource struct {
+	File      string    `json:"file"`
+	URL       string    `json:"url,omitempty"`
+	ScrapedAt time.Time `json:"scraped_at"`
+}
+
+// mergeResults combines results saved with -format json. Links, texts and
+// images are deduplicated, each recording in Sources (ImageSources for
+// images) the pages it was found on, or the file when a result has no URL.
+// The other fields are merged as in a crawl.
+func mergeResults(paths []string) (ScrapeData, error) {
+	var merged ScrapeData
+	var links []Link
+	var texts []TextEntry
+	var images []string
+	linkIndex := make(map[string]int)
+	textIndex := make(map[string]int)
+	imageSources := make(map[string][]string)
+
+	for _, path := range paths {
+		data, err := loadResult(path)
+		if err != nil {
+			return ScrapeData{}, err
+		}
+		source := firstNonEmpty(data.URL, path)
+		if len(data.MergedFrom) > 0 {
+			// A result of an earlier -merge lists the files it came from
+			merged.MergedFrom = append(merged.MergedFrom, data.MergedFrom...)
+		} else {
+			merged.MergedFrom = append(merged.MergedFrom, MergeSource{File: path, URL: data.URL, ScrapedAt: data.ScrapedAt})
+		}
+
+		for _, link := range data.Links {
+			previous := link.Sources
+			i, ok := linkIndex[link.URL]
+			if !ok {
+				i = len(links)
+				linkIndex[link.URL] = i
+				link.Sources = nil
+				links = append(links, link)
+			}
+			links[i].Sources = appendSource(links[i].Sources, previous, source)
+		}
+		for _, text := range data.Texts {
+			previous := text.Sources
+			i, ok := textIndex[text.Text]
+			if !ok {
+				i = len(texts)
+				textIndex[text.Text] = i
+				text.Sources = nil
+				texts = append(texts, text)
+			}
+			texts[i].Sources = appendSource(texts[i].Sources, previous, source)
+		}
+		for _, src := range data.Images {
+			if _, ok := imageSources[src]; !ok {
+				images = append(images, src)
+			}
+			imageSources[src] = appendSource(imageSources[src], data.ImageSources[src], source)
+		}
+
+		from := merged.MergedFrom
+		data.Links, data.Texts, data.Images, data.ImageSources, data.MergedFrom = nil, nil, nil, nil, nil
+		merged = mergeData(merged, data)
+		merged.MergedFrom = from
+	}
+	merged.Links, merged.Texts, merged.Images = links, texts, images
+	if len(images) > 0 {
+		merged.ImageSources = imageSources
+	}
+	return merged, nil
+}
+
+// appendSource adds the sources an item was already attributed to in a
+// merged file, or else source, to sources without repeating any.
+func appendSource(sources, previous []string, source string) []string {
+	if len(previous) == 0 {
+		previous = []string{source}
+	}
+	for _, s := range previous {
+		found := false
+		for _, existing := range sources {
+			if existing == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// DOMMetrics describes the structure of a page's parsed DOM.
+type DOMMetrics struct {
+	Elements  int            `json:"elements"`
+	MaxDepth  int            `json:"max_depth"`  // <html> is at depth 1
+	TextNodes int            `json:"text_nodes"` // Excluding whitespace-only nodes
+	Tags      map[string]int `json:"tags"`
+}
+
+// extractDOMMetrics walks the whole parsed tree, including content the
+// other extractors ignore such as <script> and <head>.
+func extractDOMMetrics(doc *goquery.Document) *DOMMetrics {
+	m := &DOMMetrics{Tags: make(map[string]int)}
+	var walk func(n *html.Node, depth int)
+	walk = func(n *html.Node, depth int) {
+		switch n.Type {
+		case html.ElementNode:
+			depth++
+			m.Elements++
+			m.Tags[n.Data]++
+			if depth > m.MaxDepth {
+				m.MaxDepth = depth
+			}
+		case html.TextNode:
+			if strings.TrimSpace(n.Data) != "" {
+				m.TextNodes++
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, depth)
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n, 0)
+	}
+	return m
+}
+
+// add folds the metrics of another page into m: counts are summed and the
+// depth is the deepest of the two.
+func (m *DOMMetrics) add(o *DOMMetrics) {
+	m.Elements += o.Elements
+	m.TextNodes += o.TextNodes
+	m.MaxDepth = max(m.MaxDepth, o.MaxDepth)
+	if m.Tags == nil {
+		m.Tags = make(map[string]int)
+	}
+	for tag, n := range o.Tags {
+		m.Tags[tag] += n
+	}
+}
+
+// String formats the metrics for plain-text output, most used tags first.
+func (m *DOMMetrics) String() string {
+	tags := make([]string, 0, len(m.Tags))
+	for tag := range m.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if m.Tags[tags[i]] != m.Tags[tags[j]] {
+			return m.Tags[tags[i]] > m.Tags[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = fmt.Sprintf("%s=%d", tag, m.Tags[tag])
+	}
+	return fmt.Sprintf("%d elements, max depth %d, %d text nodes\n%s", m.Elements, m.MaxDepth, m.TextNodes, strings.Join(parts, " "))
+}
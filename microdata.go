@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// microdataScope returns the first element declaring itemtype typ, such as
+// "Product", or an empty selection if there is none.
+func microdataScope(doc *goquery.Document, typ string) *goquery.Selection {
+	return doc.Find("[itemscope][itemtype]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		t, _ := s.Attr("itemtype")
+		return schemaEnum(strings.TrimSpace(t)) == typ
+	}).First()
+}
+
+// itemprop returns the value of the first itemprop named prop inside scope.
+func itemprop(scope *goquery.Selection, prop string) string {
+	s := scope.Find(`[itemprop~="` + prop + `"]`).First()
+	if s.Length() == 0 {
+		return ""
+	}
+	return itempropValue(s)
+}
+
+// itemprops returns the values of every itemprop named prop inside scope.
+func itemprops(scope *goquery.Selection, prop string) []string {
+	var values []string
+	scope.Find(`[itemprop~="` + prop + `"]`).Each(func(i int, s *goquery.Selection) {
+		if v := itempropValue(s); v != "" {
+			values = append(values, v)
+		}
+	})
+	return values
+}
+
+// itempropValue reads a microdata property from its content, datetime, href
+// or src attribute, or else its text.
+func itempropValue(s *goquery.Selection) string {
+	for _, attr := range []string{"content", "datetime", "href", "src"} {
+		if v, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
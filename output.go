@@ -0,0 +1,637 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// writeText writes the scraped data as numbered plain-text lists.
+func writeText(w io.Writer, data ScrapeData) {
+	if data.Status != 0 && data.Status != http.StatusOK {
+		fmt.Fprintf(w, "Status: %d %s\n", data.Status, http.StatusText(data.Status))
+	}
+	if data.Title != "" {
+		fmt.Fprintf(w, "Title: %s\n", data.Title)
+	}
+	for _, a := range data.Author {
+		fmt.Fprintf(w, "Author: %s\n", a)
+	}
+	if data.Publisher != nil {
+		fmt.Fprintf(w, "Publisher: %s\n", data.Publisher)
+	}
+	if data.LastModified != nil {
+		fmt.Fprintf(w, "Last Modified: %s\n", data.LastModified.Format(time.RFC3339))
+	}
+	if data.AMPURL != "" {
+		fmt.Fprintf(w, "AMP URL: %s\n", data.AMPURL)
+	}
+	if data.PrintURL != "" {
+		fmt.Fprintf(w, "Print URL: %s\n", data.PrintURL)
+	}
+	if data.RefreshURL != "" {
+		fmt.Fprintf(w, "Meta Refresh: %s\n", data.RefreshURL)
+	}
+	if data.CanonicalFrom != "" {
+		fmt.Fprintf(w, "Canonical Of: %s\n", data.CanonicalFrom)
+	}
+	if data.Encoding != nil {
+		fmt.Fprintf(w, "Encoding: %s\n", data.Encoding)
+	}
+	for _, warning := range data.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+	for _, e := range data.Errors {
+		fmt.Fprintf(w, "Failed: %s\n", e)
+	}
+	if (data.Status != 0 && data.Status != http.StatusOK) || data.Title != "" || len(data.Author) > 0 || data.Publisher != nil || data.LastModified != nil || data.AMPURL != "" || data.PrintURL != "" || data.RefreshURL != "" || data.CanonicalFrom != "" || data.Encoding != nil || len(data.Warnings) > 0 || len(data.Errors) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "Scraped Links:")
+	for i, link := range data.Links {
+		fmt.Fprintf(w, "%d. %s\n", i+1, link)
+	}
+
+	fmt.Fprintln(w, "\nScraped Text (Paragraphs):")
+	for i, text := range data.Texts {
+		fmt.Fprintf(w, "%d. %s\n", i+1, text)
+	}
+
+	fmt.Fprintln(w, "\nScraped Images:")
+	for i, src := range data.Images {
+		if dims, ok := data.ImageDimensions[src]; ok {
+			fmt.Fprintf(w, "%d. %s (%dx%d)\n", i+1, src, dims.Width, dims.Height)
+		} else {
+			fmt.Fprintf(w, "%d. %s\n", i+1, src)
+		}
+	}
+	for i, img := range data.InlineImages {
+		fmt.Fprintf(w, "%d. %s\n", len(data.Images)+i+1, img)
+	}
+	if len(data.DownloadedImages) > 0 {
+		fmt.Fprintf(w, "\nDownloaded Images: %d files, %d duplicates collapsed\n", len(data.DownloadedImages), duplicateImages(data.DownloadedImages))
+		for i, img := range data.DownloadedImages {
+			if sum := img.SHA256; sum != "" {
+				// Loaded results may carry a short or missing hash
+				fmt.Fprintf(w, "%d. %s (sha256 %s)\n", i+1, img.File, sum[:min(len(sum), 12)])
+			} else {
+				fmt.Fprintf(w, "%d. %s\n", i+1, img.File)
+			}
+			if len(img.URLs) > 1 {
+				for _, u := range img.URLs {
+					fmt.Fprintf(w, "   - %s\n", u)
+				}
+			}
+		}
+	}
+
+	if len(data.MainText) > 0 {
+		fmt.Fprintln(w, "\nMain Text:")
+		for i, text := range data.MainText {
+			fmt.Fprintf(w, "%d. %s\n", i+1, text)
+		}
+	}
+
+	if data.Recipe != nil {
+		fmt.Fprintf(w, "\nRecipe: %s\n", data.Recipe)
+	}
+	if data.Product != nil {
+		fmt.Fprintf(w, "\nProduct: %s\n", data.Product)
+	}
+
+	if data.Rating != nil {
+		fmt.Fprintf(w, "\nRating: %s\n", data.Rating)
+	}
+
+	if data.Viewport != nil {
+		fmt.Fprintf(w, "\nViewport: %s\n", data.Viewport)
+	}
+
+	if len(data.Inconsistencies) > 0 {
+		fmt.Fprintln(w, "\nInconsistencies:")
+		for i, inc := range data.Inconsistencies {
+			fmt.Fprintf(w, "%d. %s\n", i+1, inc)
+		}
+	}
+
+	if data.PageWeight != nil {
+		fmt.Fprintf(w, "\nPage Weight: %s\n", data.PageWeight)
+	}
+
+	if data.DOMMetrics != nil {
+		fmt.Fprintf(w, "\nDOM Metrics: %s\n", data.DOMMetrics)
+	}
+
+	if len(data.Templates) > 0 {
+		fmt.Fprintln(w, "\nTemplates:")
+		for i, t := range data.Templates {
+			fmt.Fprintf(w, "%d. <%s>%s %s\n", i+1, t.Tag, idSuffix(t.ID), strings.Join(strings.Fields(t.HTML), " "))
+		}
+	}
+
+	if len(data.Videos) > 0 {
+		fmt.Fprintln(w, "\nVideos:")
+		for i, v := range data.Videos {
+			fmt.Fprintf(w, "%d. %s\n", i+1, v)
+		}
+	}
+
+	if len(data.Breadcrumbs) > 0 {
+		fmt.Fprintf(w, "\nBreadcrumbs: %s\n", formatTrail(data.Breadcrumbs))
+	}
+	if len(data.SiteTree) > 0 {
+		fmt.Fprintln(w, "\nSite Tree:")
+		writeSiteTree(w, data.SiteTree, "")
+	}
+
+	if len(data.Profile) > 0 {
+		fmt.Fprintf(w, "\nProfile: %s\n", formatProfile(data.Profile))
+	}
+
+	if len(data.Events) > 0 {
+		fmt.Fprintln(w, "\nEvents:")
+		for i, e := range data.Events {
+			fmt.Fprintf(w, "%d. %s\n", i+1, e)
+		}
+	}
+
+	if len(data.FAQ) > 0 {
+		fmt.Fprintln(w, "\nFAQ:")
+		for i, qa := range data.FAQ {
+			fmt.Fprintf(w, "%d. %s\n", i+1, qa)
+		}
+	}
+
+	if len(data.LocalizedValues) > 0 {
+		fmt.Fprintln(w, "\nParsed Values:")
+		for i, v := range data.LocalizedValues {
+			fmt.Fprintf(w, "%d. %s\n", i+1, v)
+		}
+	}
+
+	if len(data.Prices) > 0 {
+		fmt.Fprintln(w, "\nPrices:")
+		for i, p := range data.Prices {
+			fmt.Fprintf(w, "%d. %s\n", i+1, p)
+		}
+	}
+
+	if len(data.Alternates) > 0 {
+		fmt.Fprintln(w, "\nAlternates:")
+		for _, lang := range sortedKeys(data.Alternates) {
+			fmt.Fprintf(w, "- %s: %s\n", lang, data.Alternates[lang])
+		}
+	}
+
+	if len(data.Headings) > 0 {
+		fmt.Fprintln(w, "\nHeadings:")
+		for _, h := range data.Headings {
+			fmt.Fprintf(w, "- %s\n", h)
+		}
+	}
+
+	if len(data.Times) > 0 {
+		fmt.Fprintln(w, "\nTimes:")
+		for i, t := range data.Times {
+			fmt.Fprintf(w, "%d. %s\n", i+1, t)
+		}
+	}
+
+	if len(data.Tables) > 0 {
+		fmt.Fprintln(w, "\nTables:")
+		for i, t := range data.Tables {
+			fmt.Fprintf(w, "%d. %s\n", i+1, t)
+		}
+	}
+
+	if len(data.ResourceHints) > 0 {
+		fmt.Fprintln(w, "\nResource Hints:")
+		for i, h := range data.ResourceHints {
+			fmt.Fprintf(w, "%d. %s\n", i+1, h)
+		}
+	}
+
+	if len(data.ThirdPartyScripts) > 0 {
+		fmt.Fprintln(w, "\nThird-Party Scripts:")
+		for i, host := range sortedKeys(data.ThirdPartyScripts) {
+			fmt.Fprintf(w, "%d. %s (%d)\n", i+1, host, data.ThirdPartyScripts[host])
+		}
+	}
+
+	if len(data.BrokenAnchors) > 0 {
+		fmt.Fprintln(w, "\nBroken Anchors:")
+		for i, a := range data.BrokenAnchors {
+			fmt.Fprintf(w, "%d. %s\n", i+1, a)
+		}
+	}
+
+	if len(data.Cookies) > 0 {
+		fmt.Fprintln(w, "\nCookies:")
+		for i, c := range data.Cookies {
+			fmt.Fprintf(w, "%d. %s\n", i+1, c)
+		}
+	}
+
+	if len(data.IFrames) > 0 {
+		fmt.Fprintln(w, "\nIFrames:")
+		for i, src := range data.IFrames {
+			fmt.Fprintf(w, "%d. %s\n", i+1, src)
+		}
+	}
+
+	for _, kind := range sortedKeys(data.Media) {
+		fmt.Fprintf(w, "\nMedia (%s):\n", kind)
+		for i, src := range data.Media[kind] {
+			fmt.Fprintf(w, "%d. %s\n", i+1, src)
+		}
+	}
+
+	for _, name := range sortedKeys(data.Selections) {
+		fmt.Fprintf(w, "\nSelected %s:\n", name)
+		for i, v := range data.Selections[name] {
+			fmt.Fprintf(w, "%d. %s\n", i+1, v)
+		}
+	}
+
+	if len(data.Accessibility) > 0 {
+		fmt.Fprintln(w, "\nAccessibility:")
+		for _, tag := range sortedKeys(data.Accessibility) {
+			for _, entry := range data.Accessibility[tag] {
+				fmt.Fprintf(w, "- <%s> %s\n", tag, entry)
+			}
+		}
+	}
+}
+
+// itemCount is the number of items extracted in one category.
+type itemCount struct {
+	Name string
+	N    int
+}
+
+// itemCounts returns the item counts of the fixed categories followed by
+// any enabled extractors.
+func itemCounts(data ScrapeData) []itemCount {
+	counts := []itemCount{
+		{"links", len(data.Links)},
+		{"texts", len(data.Texts)},
+		{"images", len(data.Images) + len(data.InlineImages)},
+	}
+	if len(data.Errors) > 0 {
+		counts = append(counts, itemCount{"errors", len(data.Errors)})
+	}
+	if len(data.MainText) > 0 {
+		counts = append(counts, itemCount{"main_text", len(data.MainText)})
+	}
+	if len(data.Alternates) > 0 {
+		counts = append(counts, itemCount{"alternates", len(data.Alternates)})
+	}
+	if len(data.Headings) > 0 {
+		counts = append(counts, itemCount{"headings", len(data.Headings)})
+	}
+	if len(data.Times) > 0 {
+		counts = append(counts, itemCount{"times", len(data.Times)})
+	}
+	if len(data.Inconsistencies) > 0 {
+		counts = append(counts, itemCount{"inconsistencies", len(data.Inconsistencies)})
+	}
+	if len(data.Templates) > 0 {
+		counts = append(counts, itemCount{"templates", len(data.Templates)})
+	}
+	if len(data.Videos) > 0 {
+		counts = append(counts, itemCount{"videos", len(data.Videos)})
+	}
+	if len(data.Breadcrumbs) > 0 {
+		counts = append(counts, itemCount{"breadcrumbs", len(data.Breadcrumbs)})
+	}
+	if len(data.Events) > 0 {
+		counts = append(counts, itemCount{"events", len(data.Events)})
+	}
+	if len(data.FAQ) > 0 {
+		counts = append(counts, itemCount{"faq", len(data.FAQ)})
+	}
+	if len(data.LocalizedValues) > 0 {
+		counts = append(counts, itemCount{"parsed_values", len(data.LocalizedValues)})
+	}
+	if len(data.Prices) > 0 {
+		counts = append(counts, itemCount{"prices", len(data.Prices)})
+	}
+	if len(data.Tables) > 0 {
+		counts = append(counts, itemCount{"tables", len(data.Tables)})
+	}
+	if len(data.ResourceHints) > 0 {
+		counts = append(counts, itemCount{"resource_hints", len(data.ResourceHints)})
+	}
+	if len(data.ThirdPartyScripts) > 0 {
+		counts = append(counts, itemCount{"third_party_scripts", len(data.ThirdPartyScripts)})
+	}
+	if len(data.BrokenAnchors) > 0 {
+		counts = append(counts, itemCount{"broken_anchors", len(data.BrokenAnchors)})
+	}
+	if len(data.Cookies) > 0 {
+		counts = append(counts, itemCount{"cookies", len(data.Cookies)})
+	}
+	if len(data.IFrames) > 0 {
+		counts = append(counts, itemCount{"iframes", len(data.IFrames)})
+	}
+	if len(data.Accessibility) > 0 {
+		n := 0
+		for _, entries := range data.Accessibility {
+			n += len(entries)
+		}
+		counts = append(counts, itemCount{"accessibility", n})
+	}
+	for _, kind := range sortedKeys(data.Media) {
+		counts = append(counts, itemCount{kind, len(data.Media[kind])})
+	}
+	for _, name := range sortedKeys(data.Selections) {
+		counts = append(counts, itemCount{name, len(data.Selections[name])})
+	}
+	return counts
+}
+
+// countSummary returns the item counts on a single line.
+func countSummary(data ScrapeData) string {
+	var parts []string
+	for _, c := range itemCounts(data) {
+		parts = append(parts, fmt.Sprintf("%s=%d", c.Name, c.N))
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeCounts writes only the number of items in each category.
+func writeCounts(w io.Writer, data ScrapeData) {
+	for _, c := range itemCounts(data) {
+		fmt.Fprintf(w, "%-14s %d\n", c.Name+":", c.N)
+	}
+}
+
+// htmlReport is the template for -format html. html/template escapes all
+// scraped values, and unsafe URLs in href and src are replaced.
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Scrape Report</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; color: #222; }
+h1 { border-bottom: 2px solid #ddd; }
+h2 { margin-top: 2em; color: #444; }
+li { margin: 0.3em 0; word-break: break-all; }
+.path { color: #888; font-family: monospace; }
+table { border-collapse: collapse; margin: 1em 0; }
+td { border: 1px solid #ccc; padding: 0.2em 0.5em; }
+.images img { max-width: 160px; max-height: 120px; margin: 4px; border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>Scrape Report</h1>
+{{with .Status}}{{if ne . 200}}<p>Status: {{.}}</p>{{end}}{{end}}
+{{with .Title}}<p>Title: {{.}}</p>{{end}}
+{{range .Author}}<p>Author: {{with .URL}}<a href="{{.}}">{{end}}{{if .Name}}{{.Name}}{{else}}{{.URL}}{{end}}{{if .URL}}</a>{{end}}</p>
+{{end}}{{with .Publisher}}<p>Publisher: {{.}}</p>{{end}}
+{{with .LastModified}}<p>Last modified: {{.Format "2006-01-02 15:04:05 MST"}}</p>{{end}}
+{{range .Warnings}}<p><strong>Warning:</strong> {{.}}</p>
+{{end}}{{range .Errors}}<p><strong>Failed:</strong> {{.}}</p>
+{{end}}{{with .AMPURL}}<p>AMP version: <a href="{{.}}">{{.}}</a></p>{{end}}
+{{with .PrintURL}}<p>Print version: <a href="{{.}}">{{.}}</a></p>{{end}}
+{{with .RefreshURL}}<p>Meta refresh: <a href="{{.}}">{{.}}</a></p>{{end}}
+{{with .CanonicalFrom}}<p>Canonical version of: <a href="{{.}}">{{.}}</a></p>{{end}}
+{{with .Encoding}}<p>Encoding: {{.}}</p>{{end}}
+
+<h2>Links ({{len .Links}})</h2>
+<ol>
+{{range .Links}}<li><a href="{{.URL}}">{{.URL}}</a>{{with .Resolved}} &rarr; <a href="{{.}}">{{.}}</a>{{end}}{{with .Rel}} <span class="path">{{range .}}{{.}} {{end}}</span>{{end}}{{with .Context}}<br><q>{{.}}</q>{{end}}</li>
+{{end}}</ol>
+
+<h2>Text ({{len .Texts}})</h2>
+<ol>
+{{range .Texts}}<li{{with .Lang}} lang="{{.}}"{{end}}{{with .Dir}} dir="{{.}}"{{end}}>{{with .Path}}<span class="path">{{.}}</span> {{end}}{{.Text}}</li>
+{{end}}</ol>
+
+<h2>Images ({{len .Images}}{{with .InlineImages}} + {{len .}} inline{{end}})</h2>
+<div class="images">
+{{range .Images}}<a href="{{.}}"><img src="{{.}}" alt="{{.}}"{{with index $.ImageDimensions .}}{{if .Width}} width="{{.Width}}" height="{{.Height}}"{{end}}{{end}}></a>
+{{end}}</div>
+{{with .InlineImages}}<ol>
+{{range .}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}{{with .DownloadedImages}}
+<h2>Downloaded Images ({{len .}})</h2>
+<ol>
+{{range .}}<li>{{.File}} <span class="path">{{.SHA256}}</span>{{if gt (len .URLs) 1}}<ul>{{range .URLs}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul>{{end}}</li>
+{{end}}</ol>
+{{end}}{{with .MainText}}
+<h2>Main Text ({{len .}})</h2>
+{{range .}}<p>{{.}}</p>
+{{end}}{{end}}{{with .Recipe}}
+<h2>Recipe{{with .Name}}: {{.}}{{end}}</h2>
+{{with .PrepTime}}<p>Prep time: {{.}}</p>{{end}}{{with .CookTime}}<p>Cook time: {{.}}</p>{{end}}{{with .TotalTime}}<p>Total time: {{.}}</p>{{end}}{{with .Yield}}<p>Yield: {{.}}</p>{{end}}
+{{with .Ingredients}}<h3>Ingredients</h3>
+<ul>
+{{range .}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{with .Instructions}}<h3>Instructions</h3>
+<ol>
+{{range .}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}{{with .Nutrition}}<h3>Nutrition</h3>
+<dl>
+{{range $k, $v := .}}<dt>{{$k}}</dt><dd>{{$v}}</dd>
+{{end}}</dl>
+{{end}}{{end}}{{with .Product}}
+<h2>Product</h2>
+<p>{{.}}</p>
+{{end}}{{with .Viewport}}
+<h2>Viewport</h2>
+<p>{{.}}</p>
+{{end}}{{with .Rating}}
+<h2>Rating</h2>
+<p>{{.}}</p>
+{{end}}{{with .Inconsistencies}}
+<h2>Inconsistencies ({{len .}})</h2>
+<ul>
+{{range .}}<li>{{.Field}}{{with .URL}} on <a href="{{.}}">{{.}}</a>{{end}}<ul>{{range $source, $v := .Values}}<li>{{$source}}: {{$v}}</li>{{end}}</ul></li>
+{{end}}</ul>
+{{end}}{{with .PageWeight}}
+<h2>Page Weight</h2>
+<p>{{.}}</p>
+{{end}}{{with .DOMMetrics}}
+<h2>DOM Metrics</h2>
+<p>{{.Elements}} elements, max depth {{.MaxDepth}}, {{.TextNodes}} text nodes</p>
+<ul>
+{{range $tag, $n := .Tags}}<li>{{$tag}}: {{$n}}</li>
+{{end}}</ul>
+{{end}}{{with .Templates}}
+<h2>Templates ({{len .}})</h2>
+<ol>
+{{range .}}<li>&lt;{{.Tag}}&gt;{{with .ID}} #{{.}}{{end}}<pre>{{.HTML}}</pre></li>
+{{end}}</ol>
+{{end}}{{with .Videos}}
+<h2>Videos ({{len .}})</h2>
+<ol>
+{{range .}}<li>{{with .Thumbnail}}<img src="{{.}}" alt=""> {{end}}{{.}}{{with .Description}}<br>{{.}}{{end}}</li>
+{{end}}</ol>
+{{end}}{{with .Breadcrumbs}}
+<h2>Breadcrumbs</h2>
+<p>{{range $i, $c := .}}{{if $i}} &gt; {{end}}{{if $c.URL}}<a href="{{$c.URL}}">{{$c.Name}}</a>{{else}}{{$c.Name}}{{end}}{{end}}</p>
+{{end}}{{with .SiteTree}}
+<h2>Site Tree</h2>
+{{template "siteNodes" .}}
+{{end}}{{with .Profile}}
+<h2>Profile</h2>
+<table>
+<tr><th>Stage</th><th>Seconds</th></tr>
+{{range .}}<tr><td>{{.Stage}}</td><td>{{printf "%.6f" .Seconds}}</td></tr>
+{{end}}</table>
+{{end}}{{with .Events}}
+<h2>Events ({{len .}})</h2>
+<ol>
+{{range .}}<li>{{if .URL}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{with .Start}} &middot; {{.Format "2006-01-02 15:04 MST"}}{{end}}{{with .Location}} &middot; {{.}}{{end}}{{with .Price}} &middot; {{.}}{{end}}</li>
+{{end}}</ol>
+{{end}}{{with .FAQ}}
+<h2>FAQ ({{len .}})</h2>
+<dl>
+{{range .}}<dt>{{.Question}}</dt><dd>{{.Answer}}</dd>
+{{end}}</dl>
+{{end}}{{with .LocalizedValues}}
+<h2>Parsed Values ({{len .}})</h2>
+<table>
+<tr><th>Text</th><th>Kind</th><th>Value</th></tr>
+{{range .}}<tr><td>{{.Raw}}</td><td>{{.Kind}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}{{with .Prices}}
+<h2>Prices ({{len .}})</h2>
+<ol>
+{{range .}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}{{with .Alternates}}
+<h2>Alternates ({{len .}})</h2>
+<ul>
+{{range $lang, $href := .}}<li>{{$lang}}: <a href="{{$href}}">{{$href}}</a></li>
+{{end}}</ul>
+{{end}}{{with .Headings}}
+<h2>Headings ({{len .}})</h2>
+<ul>
+{{range .}}<li style="margin-left: {{.Level}}em">{{if .Anchor}}<a href="{{.Anchor}}">{{.Text}}</a>{{else}}{{.Text}}{{end}}</li>
+{{end}}</ul>
+{{end}}{{with .Times}}
+<h2>Times ({{len .}})</h2>
+<ol>
+{{range .}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}{{with .Tables}}
+<h2>Tables ({{len .}})</h2>
+{{range .}}<table>
+{{with .Caption}}<caption>{{.}}</caption>
+{{end}}{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{end}}{{with .ResourceHints}}
+<h2>Resource Hints ({{len .}})</h2>
+<ol>
+{{range .}}<li>{{.Rel}} <a href="{{.Href}}">{{.Href}}</a>{{with .As}} (as {{.}}){{end}}</li>
+{{end}}</ol>
+{{end}}{{with .ThirdPartyScripts}}
+<h2>Third-Party Scripts ({{len .}})</h2>
+<ol>
+{{range $host, $n := .}}<li>{{$host}} ({{$n}})</li>
+{{end}}</ol>
+{{end}}{{with .BrokenAnchors}}
+<h2>Broken Anchors ({{len .}})</h2>
+<ol>
+{{range .}}<li><code>{{.}}</code></li>
+{{end}}</ol>
+{{end}}{{with .Cookies}}
+<h2>Cookies ({{len .}})</h2>
+<ol>
+{{range .}}<li><code>{{.}}</code></li>
+{{end}}</ol>
+{{end}}{{with .IFrames}}
+<h2>IFrames ({{len .}})</h2>
+<ol>
+{{range .}}<li><a href="{{.}}">{{.}}</a></li>
+{{end}}</ol>
+{{end}}{{range $kind, $urls := .Media}}
+<h2>Media: {{$kind}} ({{len $urls}})</h2>
+<ol>
+{{range $urls}}<li><a href="{{.}}">{{.}}</a></li>
+{{end}}</ol>
+{{end}}{{range $name, $values := .Selections}}
+<h2>Selected {{$name}} ({{len $values}})</h2>
+<ol>
+{{range $values}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}{{with .Accessibility}}
+<h2>Accessibility</h2>
+<ul>
+{{range $tag, $entries := .}}{{range $entries}}<li><code>&lt;{{$tag}}&gt;</code> {{.}}</li>
+{{end}}{{end}}</ul>
+{{end}}
+</body>
+</html>
+{{define "siteNodes"}}<ul>
+{{range .}}<li>{{if .URL}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}} ({{.Pages}}){{with .Children}}{{template "siteNodes" .}}{{end}}</li>
+{{end}}</ul>{{end}}`))
+
+// writeHTML renders the scraped data as a styled HTML report.
+func writeHTML(w io.Writer, data ScrapeData) error {
+	return htmlReport.Execute(w, data)
+}
+
+// validFormat reports whether format is a supported output format.
+func validFormat(format string) bool {
+	switch format {
+	case "text", "json", "jsonl", "html", "parquet", "dot", "es-bulk":
+		return true
+	}
+	return false
+}
+
+// writeOutput writes the scraped data to w in the given format.
+func writeOutput(w io.Writer, data ScrapeData, format string) error {
+	switch format {
+	case "json":
+		if len(outputFields) > 0 {
+			return writeMappedJSON(w, data, outputFields)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(data)
+	case "jsonl":
+		return writeJSONItems(w, data)
+	case "html":
+		return writeHTML(w, data)
+	case "parquet":
+		return writeParquet(w, data)
+	case "dot":
+		return writeDot(w, data)
+	case "es-bulk":
+		return writeESBulk(w, data)
+	default:
+		writeText(w, data)
+		return nil
+	}
+}
+
+// saveToFile writes the scraped data to a file in the given format.
+func saveToFile(data ScrapeData, filename, format string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := writeOutput(writer, data, format); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	return writer.Flush()
+}
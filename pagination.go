@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parsePageRange parses a range like "1-10" into its first and last page.
+// A single number N is treated as the range 1-N.
+func parsePageRange(s string) (int, int, error) {
+	first, last, found := strings.Cut(s, "-")
+	if !found {
+		first, last = "1", s
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid page range %q", s)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid page range %q", s)
+	}
+	return from, to, nil
+}
+
+// defaultMaxPages caps offset pagination when -max-pages is not set, since
+// it has no natural last page.
+const defaultMaxPages = 100
+
+// pageValues returns the values of the page parameter to request in order:
+// -page-start increased by -page-step when a step is set, otherwise the
+// -page-range numbers. -max-pages limits how many there are.
+func pageValues(opts Options) ([]int, error) {
+	limit := opts.MaxPages
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid -max-pages %d", limit)
+	}
+	var values []int
+	if opts.PageStep != 0 {
+		if opts.PageStep < 0 {
+			return nil, fmt.Errorf("invalid -page-step %d", opts.PageStep)
+		}
+		if limit == 0 {
+			limit = defaultMaxPages
+		}
+		for i := 0; i < limit; i++ {
+			values = append(values, opts.PageStart+i*opts.PageStep)
+		}
+		return values, nil
+	}
+	from, to, err := parsePageRange(opts.PageRange)
+	if err != nil {
+		return nil, err
+	}
+	for page := from; page <= to && (limit == 0 || len(values) < limit); page++ {
+		values = append(values, page)
+	}
+	return values, nil
+}
+
+// pageURL returns start with the query parameter param set to page.
+func pageURL(start *url.URL, param string, page int) string {
+	u := *start
+	q := u.Query()
+	q.Set(param, strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// paginate scrapes start once for every value from pageValues, passed in
+// the opts.PageParam query parameter, and merges the results. It stops
+// early when a page is not found or adds nothing new, which is how offset
+// pagination finds its end.
+func paginate(ctx context.Context, start string, opts Options) (ScrapeData, error) {
+	startURL, err := url.Parse(start)
+	if err != nil {
+		return ScrapeData{}, fmt.Errorf("error parsing URL: %v", err)
+	}
+	pages, err := pageValues(opts)
+	if err != nil {
+		return ScrapeData{}, err
+	}
+
+	var all ScrapeData
+	seen := make(map[string]bool)
+	for _, page := range pages {
+		u := pageURL(startURL, opts.PageParam, page)
+		data, err := scrapePage(ctx, u, opts)
+		if err != nil {
+			var se *statusError
+			if errors.As(err, &se) && se.Code == http.StatusNotFound {
+				log.Printf("Stopping pagination at %s=%d: not found", opts.PageParam, page)
+				break
+			}
+			return all, err
+		}
+
+		if countNew(data, seen) == 0 {
+			log.Printf("Stopping pagination at %s=%d: no new content", opts.PageParam, page)
+			break
+		}
+		all = mergeData(all, data)
+	}
+	return all, nil
+}
+
+// countNew records every item in data in seen and returns how many of them
+// had not been seen before.
+func countNew(data ScrapeData, seen map[string]bool) int {
+	n := 0
+	mark := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			n++
+		}
+	}
+	for _, link := range data.Links {
+		mark("link:" + link.URL)
+	}
+	for _, text := range data.Texts {
+		mark("text:" + text.Text)
+	}
+	for _, src := range data.Images {
+		mark("image:" + src)
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ParquetRow is the flattened schema written by -format parquet: one row
+// per extracted item, labelled with the page it came from.
+type ParquetRow struct {
+	URL       string    `parquet:"url" json:"url"`
+	Type      string    `parquet:"type" json:"type"` // link, text, image, or the -select name
+	Value     string    `parquet:"value" json:"value"`
+	ScrapedAt time.Time `parquet:"scraped_at,timestamp(millisecond)" json:"scraped_at"`
+}
+
+// parquetRows flattens the data scraped from one page into rows.
+func parquetRows(data ScrapeData) []ParquetRow {
+	var rows []ParquetRow
+	add := func(typ, value string) {
+		rows = append(rows, ParquetRow{URL: data.URL, Type: typ, Value: value, ScrapedAt: data.ScrapedAt})
+	}
+	for _, link := range data.Links {
+		add("link", link.URL)
+	}
+	for _, text := range data.Texts {
+		add("text", text.Text)
+	}
+	for _, src := range data.Images {
+		add("image", src)
+	}
+	for _, src := range data.IFrames {
+		add("iframe", src)
+	}
+	for _, kind := range sortedKeys(data.Media) {
+		for _, src := range data.Media[kind] {
+			add(kind, src)
+		}
+	}
+	for _, name := range sortedKeys(data.Selections) {
+		for _, v := range data.Selections[name] {
+			add(name, v)
+		}
+	}
+	return rows
+}
+
+// writeParquet writes the scraped data as a Parquet file in one go: the
+// rows of each page of a crawl, or those of the single page scraped.
+func writeParquet(w io.Writer, data ScrapeData) error {
+	rows := data.ParquetRows
+	if len(rows) == 0 {
+		rows = parquetRows(data)
+	}
+	return parquet.Write(w, rows)
+}
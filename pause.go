@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pausePollInterval is how often a paused crawl checks whether its
+// -pause-file has been removed.
+const pausePollInterval = time.Second
+
+// pauseRequested reports whether the -pause-file exists.
+func pauseRequested(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// waitForResume blocks until the pause file is removed, returning true, or
+// ctx is cancelled, returning false.
+func waitForResume(ctx context.Context, path string) bool {
+	ticker := time.NewTicker(pausePollInterval)
+	defer ticker.Stop()
+	for pauseRequested(path) {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// progress describes how far the crawl has got.
+func (s *crawlState) progress() string {
+	return fmt.Sprintf("%d pages fetched, %d queued, %d seen", s.Fetched, len(s.Pending), len(s.Visited))
+}
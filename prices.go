@@ -0,0 +1,171 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Price is a price found on the page, normalized to a number and an ISO
+// 4217 currency code where they could be determined.
+type Price struct {
+	Text     string   `json:"text"`
+	Amount   *float64 `json:"amount,omitempty"`
+	Currency string   `json:"currency,omitempty"`
+}
+
+// currencySymbols maps currency symbols to ISO codes. Longer symbols come
+// first so "US$" is matched before "$".
+var currencySymbols = []struct{ symbol, code string }{
+	{"US$", "USD"}, {"C$", "CAD"}, {"CA$", "CAD"}, {"A$", "AUD"}, {"AU$", "AUD"},
+	{"NZ$", "NZD"}, {"HK$", "HKD"}, {"R$", "BRL"}, {"zł", "PLN"}, {"Kč", "CZK"},
+	{"€", "EUR"}, {"£", "GBP"}, {"¥", "JPY"}, {"₹", "INR"}, {"₽", "RUB"},
+	{"₩", "KRW"}, {"₺", "TRY"}, {"₪", "ILS"}, {"฿", "THB"}, {"$", "USD"},
+}
+
+// currencyCode matches a three-letter word that may be an ISO currency
+// code; only those in isoCurrencies are taken as one, so words like "NOW"
+// or "NEW" next to a price are not.
+var currencyCode = regexp.MustCompile(`\b[A-Z]{3}\b`)
+
+// isoCurrencies is the set of active ISO 4217 currency codes.
+var isoCurrencies = map[string]bool{
+	"AED": true, "AFN": true, "ALL": true, "AMD": true, "ANG": true,
+	"AOA": true, "ARS": true, "AUD": true, "AWG": true, "AZN": true,
+	"BAM": true, "BBD": true, "BDT": true, "BGN": true, "BHD": true,
+	"BIF": true, "BMD": true, "BND": true, "BOB": true, "BRL": true,
+	"BSD": true, "BTN": true, "BWP": true, "BYN": true, "BZD": true,
+	"CAD": true, "CDF": true, "CHF": true, "CLP": true, "CNY": true,
+	"COP": true, "CRC": true, "CUP": true, "CVE": true, "CZK": true,
+	"DJF": true, "DKK": true, "DOP": true, "DZD": true, "EGP": true,
+	"ERN": true, "ETB": true, "EUR": true, "FJD": true, "FKP": true,
+	"GBP": true, "GEL": true, "GHS": true, "GIP": true, "GMD": true,
+	"GNF": true, "GTQ": true, "GYD": true, "HKD": true, "HNL": true,
+	"HTG": true, "HUF": true, "IDR": true, "ILS": true, "INR": true,
+	"IQD": true, "IRR": true, "ISK": true, "JMD": true, "JOD": true,
+	"JPY": true, "KES": true, "KGS": true, "KHR": true, "KMF": true,
+	"KPW": true, "KRW": true, "KWD": true, "KYD": true, "KZT": true,
+	"LAK": true, "LBP": true, "LKR": true, "LRD": true, "LSL": true,
+	"LYD": true, "MAD": true, "MDL": true, "MGA": true, "MKD": true,
+	"MMK": true, "MNT": true, "MOP": true, "MRU": true, "MUR": true,
+	"MVR": true, "MWK": true, "MXN": true, "MYR": true, "MZN": true,
+	"NAD": true, "NGN": true, "NIO": true, "NOK": true, "NPR": true,
+	"NZD": true, "OMR": true, "PAB": true, "PEN": true, "PGK": true,
+	"PHP": true, "PKR": true, "PLN": true, "PYG": true, "QAR": true,
+	"RON": true, "RSD": true, "RUB": true, "RWF": true, "SAR": true,
+	"SBD": true, "SCR": true, "SDG": true, "SEK": true, "SGD": true,
+	"SHP": true, "SLE": true, "SOS": true, "SRD": true, "SSP": true,
+	"STN": true, "SVC": true, "SYP": true, "SZL": true, "THB": true,
+	"TJS": true, "TMT": true, "TND": true, "TOP": true, "TRY": true,
+	"TTD": true, "TWD": true, "TZS": true, "UAH": true, "UGX": true,
+	"USD": true, "UYU": true, "UZS": true, "VES": true, "VND": true,
+	"VUV": true, "WST": true, "XAF": true, "XCD": true, "XOF": true,
+	"XPF": true, "YER": true, "ZAR": true, "ZMW": true, "ZWL": true,
+}
+
+// priceNumber matches the number in a price, with any grouping and decimal
+// separators.
+var priceNumber = regexp.MustCompile(`\d[\d.,'\s\x{00a0}\x{202f}]*`)
+
+// parsePrice normalizes a displayed price such as "1.234,56 €" or
+// "$1,234.56". When both '.' and ',' appear the last one is the decimal
+// separator; a single separator followed by exactly three digits is taken
+// as a thousands separator.
+func parsePrice(s string) Price {
+	p := Price{Text: strings.TrimSpace(s)}
+	for _, code := range currencyCode.FindAllString(s, -1) {
+		if isoCurrencies[code] {
+			p.Currency = code
+			break
+		}
+	}
+	if p.Currency == "" {
+		for _, c := range currencySymbols {
+			if strings.Contains(s, c.symbol) {
+				p.Currency = c.code
+				break
+			}
+		}
+	}
+
+	num := strings.TrimRightFunc(priceNumber.FindString(s), func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	num = strings.Map(func(r rune) rune {
+		if r == '\'' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, num)
+	if num == "" {
+		return p
+	}
+	if amount, err := strconv.ParseFloat(normalizeNumber(num, 0), 64); err == nil {
+		p.Amount = &amount
+	}
+	return p
+}
+
+// normalizeNumber rewrites a number written with '.' and ',' separators
+// in Go syntax. When both appear the last one is the decimal separator. A
+// single separator is the decimal separator if it equals decimal, or, when
+// decimal is 0, unless it is followed by exactly three digits. A leading
+// minus sign is kept.
+func normalizeNumber(num string, decimal byte) string {
+	dot, comma := strings.LastIndex(num, "."), strings.LastIndex(num, ",")
+	sep := byte(0)
+	switch {
+	case dot >= 0 && comma >= 0:
+		sep = num[max(dot, comma)]
+	case dot >= 0 || comma >= 0:
+		single := byte('.')
+		if comma >= 0 {
+			single = ','
+		}
+		last := max(dot, comma)
+		if strings.Count(num, string(single)) == 1 {
+			if decimal == 0 && len(num)-last-1 != 3 || decimal == single {
+				sep = single
+			}
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(num); i++ {
+		switch c := num[i]; {
+		case c == sep:
+			b.WriteByte('.')
+		case c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c == '-' && i == 0:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// extractPrices parses the text of every element matching selector as a
+// price.
+func extractPrices(doc *goquery.Document, selector string) []Price {
+	var prices []Price
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		if text := strings.Join(strings.Fields(s.Text()), " "); text != "" {
+			prices = append(prices, parsePrice(text))
+		}
+	})
+	return prices
+}
+
+// String formats the price for plain-text output.
+func (p Price) String() string {
+	if p.Amount == nil {
+		return p.Text
+	}
+	s := strconv.FormatFloat(*p.Amount, 'f', 2, 64)
+	if p.Currency != "" {
+		s += " " + p.Currency
+	}
+	return s + " (" + p.Text + ")"
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Query parameters that ask for a print-friendly page: printFlags when
+// present and not false, printModes when set to "print" or similar.
+var (
+	printFlags = []string{"print", "printable"}
+	printModes = []string{"view", "format", "output", "layout", "mode"}
+)
+
+// extractPrintURL returns the absolute URL of the page's print version: a
+// <link rel="alternate" media="print">, or else a same-host link whose query
+// asks for printing (?print=1, ?view=print, ...) or whose path ends in
+// /print. It returns "" if there is none.
+func extractPrintURL(doc *goquery.Document, base *url.URL) string {
+	var found string
+	doc.Find(`link[rel~="alternate"][media][href], a[rel~="alternate"][media][href]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		media, _ := s.Attr("media")
+		if mediaIncludesPrint(media) {
+			found = resolvedAttr(s, "href", base)
+		}
+		return found == ""
+	})
+	if found != "" || base == nil {
+		return found
+	}
+
+	doc.Find("a[href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		abs := resolvedAttr(s, "href", base)
+		u, err := url.Parse(abs)
+		if err != nil || !strings.EqualFold(u.Host, base.Host) || !isPrintURL(u) {
+			return true
+		}
+		found = abs
+		return false
+	})
+	return found
+}
+
+// mediaIncludesPrint reports whether a media query list names print.
+func mediaIncludesPrint(media string) bool {
+	for _, q := range strings.Split(strings.ToLower(media), ",") {
+		if fields := strings.Fields(q); len(fields) > 0 && (fields[0] == "print" || len(fields) > 1 && fields[0] == "only" && fields[1] == "print") {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrintURL reports whether u asks for a print-friendly version.
+func isPrintURL(u *url.URL) bool {
+	query := u.Query()
+	for _, param := range printFlags {
+		for _, v := range query[param] {
+			switch strings.ToLower(v) {
+			case "0", "false", "no", "off":
+			default:
+				return true
+			}
+		}
+	}
+	for _, param := range printModes {
+		for _, v := range query[param] {
+			switch strings.ToLower(v) {
+			case "print", "printable", "printer", "printer-friendly":
+				return true
+			}
+		}
+	}
+	return strings.EqualFold(path.Base(strings.TrimSuffix(u.Path, "/")), "print")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Product is schema.org Product data found on the page.
+type Product struct {
+	Name         string `json:"name,omitempty"`
+	Price        string `json:"price,omitempty"`
+	Currency     string `json:"currency,omitempty"`
+	Availability string `json:"availability,omitempty"`
+	SKU          string `json:"sku,omitempty"`
+	Rating       string `json:"rating,omitempty"`
+	Source       string `json:"source"` // json-ld, microdata or meta
+
+	Amount *float64 `json:"amount,omitempty"` // Price as a number
+}
+
+// extractProduct looks for product data in JSON-LD first, then microdata,
+// then Open Graph product meta tags, and normalizes its price. It returns nil
+// if none is found.
+func extractProduct(doc *goquery.Document) *Product {
+	p := findProduct(doc)
+	if p != nil && p.Price != "" {
+		// Structured data prices are plain numbers; anything else is parsed
+		// like a displayed price
+		if amount, err := strconv.ParseFloat(strings.TrimSpace(p.Price), 64); err == nil {
+			p.Amount = &amount
+		} else {
+			parsed := parsePrice(p.Price)
+			p.Amount = parsed.Amount
+			if p.Currency == "" {
+				p.Currency = parsed.Currency
+			}
+		}
+	}
+	return p
+}
+
+// findProduct returns the product data extractProduct normalizes.
+func findProduct(doc *goquery.Document) *Product {
+	if obj := findJSONLD(doc, "Product"); obj != nil {
+		p := &Product{
+			Name:   jsonLDString(obj, "name"),
+			SKU:    jsonLDString(obj, "sku"),
+			Source: "json-ld",
+		}
+		if offer := jsonLDObject(obj, "offers"); offer != nil {
+			p.Price = jsonLDString(offer, "price")
+			if p.Price == "" {
+				p.Price = jsonLDString(offer, "lowPrice")
+			}
+			p.Currency = jsonLDString(offer, "priceCurrency")
+			p.Availability = schemaEnum(jsonLDString(offer, "availability"))
+		}
+		if rating := jsonLDObject(obj, "aggregateRating"); rating != nil {
+			p.Rating = jsonLDString(rating, "ratingValue")
+		}
+		return p
+	}
+
+	if scope := microdataScope(doc, "Product"); scope.Length() > 0 {
+		return &Product{
+			Name:         itemprop(scope, "name"),
+			Price:        itemprop(scope, "price"),
+			Currency:     itemprop(scope, "priceCurrency"),
+			Availability: schemaEnum(itemprop(scope, "availability")),
+			SKU:          itemprop(scope, "sku"),
+			Rating:       itemprop(scope, "ratingValue"),
+			Source:       "microdata",
+		}
+	}
+
+	p := &Product{
+		Name:         metaContent(doc, "og:title"),
+		Price:        firstNonEmpty(metaContent(doc, "product:price:amount"), metaContent(doc, "og:price:amount")),
+		Currency:     firstNonEmpty(metaContent(doc, "product:price:currency"), metaContent(doc, "og:price:currency")),
+		Availability: firstNonEmpty(metaContent(doc, "product:availability"), metaContent(doc, "og:availability")),
+		SKU:          metaContent(doc, "product:retailer_item_id"),
+		Source:       "meta",
+	}
+	if p.Price == "" && p.SKU == "" {
+		return nil
+	}
+	return p
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// String formats the product for plain-text output.
+func (p Product) String() string {
+	var parts []string
+	add := func(label, v string) {
+		if v != "" {
+			parts = append(parts, label+": "+v)
+		}
+	}
+	add("Name", p.Name)
+	add("Price", strings.TrimSpace(p.Price+" "+p.Currency))
+	add("Availability", p.Availability)
+	add("SKU", p.SKU)
+	add("Rating", p.Rating)
+	return fmt.Sprintf("%s (from %s)", strings.Join(parts, ", "), p.Source)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StageTime is the wall-clock time spent in one stage of scraping a page,
+// such as fetching, parsing or an extractor, with -profile.
+type StageTime struct {
+	Stage   string  `json:"stage"`
+	Seconds float64 `json:"seconds"`
+}
+
+// pageProfile times the stages of scraping one page as laps: each lap is
+// the time since the previous one. A nil profile records nothing, so the
+// stages can be marked unconditionally.
+type pageProfile struct {
+	last   time.Time
+	stages []StageTime
+}
+
+// newPageProfile starts timing a page.
+func newPageProfile() *pageProfile {
+	return &pageProfile{last: time.Now()}
+}
+
+// lap charges the time since the previous lap to stage. Repeated stages,
+// such as the fetch of a retried page, are added up.
+func (p *pageProfile) lap(stage string) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	p.stages = addStageTime(p.stages, StageTime{Stage: stage, Seconds: now.Sub(p.last).Seconds()})
+	p.last = now
+}
+
+// reader returns r timed so that reading it is charged to the fetch stage
+// rather than to the lap in progress, such as parsing a streamed body.
+func (p *pageProfile) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &timedReader{r: r, p: p}
+}
+
+// timedReader charges the time spent in Read to its profile's fetch stage.
+type timedReader struct {
+	r io.Reader
+	p *pageProfile
+}
+
+func (t *timedReader) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(b)
+	d := time.Since(start)
+	t.p.stages = addStageTime(t.p.stages, StageTime{Stage: "fetch", Seconds: d.Seconds()})
+	// Leave the read out of the current lap
+	t.p.last = t.p.last.Add(d)
+	return n, err
+}
+
+// result returns the stages timed so far.
+func (p *pageProfile) result() []StageTime {
+	if p == nil {
+		return nil
+	}
+	return append([]StageTime(nil), p.stages...)
+}
+
+// addStageTime adds t to the stage of the same name in stages, or appends
+// it.
+func addStageTime(stages []StageTime, t StageTime) []StageTime {
+	for i := range stages {
+		if stages[i].Stage == t.Stage {
+			stages[i].Seconds += t.Seconds
+			return stages
+		}
+	}
+	return append(stages, t)
+}
+
+// mergeProfiles sums the stage times of two pages, so a crawl reports the
+// time spent in each stage across all of them.
+func mergeProfiles(a, b []StageTime) []StageTime {
+	for _, t := range b {
+		a = addStageTime(a, t)
+	}
+	return a
+}
+
+// formatProfile formats the stages on one line, slowest first, with the
+// total.
+func formatProfile(stages []StageTime) string {
+	sorted := append([]StageTime(nil), stages...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seconds > sorted[j].Seconds })
+	var total float64
+	parts := make([]string, len(sorted))
+	for i, t := range sorted {
+		total += t.Seconds
+		parts[i] = fmt.Sprintf("%s %s", t.Stage, formatSeconds(t.Seconds))
+	}
+	return fmt.Sprintf("%s (total %s)", strings.Join(parts, ", "), formatSeconds(total))
+}
+
+// formatSeconds formats a duration in seconds rounded for display.
+func formatSeconds(s float64) string {
+	d := time.Duration(s * float64(time.Second))
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// AggregateRating is a schema.org AggregateRating: the average rating of an
+// item and how many ratings or reviews it is based on.
+type AggregateRating struct {
+	Item        string  `json:"item,omitempty"` // Name of the rated item, when known
+	Value       float64 `json:"rating_value"`
+	Count       int     `json:"review_count,omitempty"` // reviewCount, or ratingCount if there is none
+	BestRating  float64 `json:"best_rating,omitempty"`
+	WorstRating float64 `json:"worst_rating,omitempty"`
+	Source      string  `json:"source"` // json-ld, microdata or markup
+}
+
+// Patterns for ratings written out in star widgets, such as "4.5 out of 5
+// stars" or "Rated 4/5", and review counts such as "(1,234 reviews)".
+var (
+	ratingText      = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(?:out of|/|of)\s*(\d+(?:[.,]\d+)?)`)
+	reviewCountText = regexp.MustCompile(`(?i)(\d[\d,.\s]*)\s*(?:reviews?|ratings?|votes?)`)
+	starSelector    = `[class*="rating"], [class*="stars"], [class*="star-rating"], [data-rating], [aria-label*="out of"], [title*="out of"]`
+)
+
+// extractRating returns the first AggregateRating in the page's JSON-LD,
+// standalone or under an item's aggregateRating, then in its microdata. As a
+// fallback it reads common star-rating markup. It returns nil if there is
+// none.
+func extractRating(doc *goquery.Document) *AggregateRating {
+	for _, obj := range jsonLDObjects(doc) {
+		if jsonLDHasType(obj, "AggregateRating") {
+			if r := jsonLDRating(obj, jsonLDString(jsonLDObject(obj, "itemReviewed"), "name")); r != nil {
+				return r
+			}
+		}
+		if rating := jsonLDObject(obj, "aggregateRating"); rating != nil {
+			if r := jsonLDRating(rating, jsonLDString(obj, "name")); r != nil {
+				return r
+			}
+		}
+	}
+
+	if scope := microdataScope(doc, "AggregateRating"); scope.Length() > 0 {
+		r := &AggregateRating{
+			Count:       parseCount(firstNonEmpty(itemprop(scope, "reviewCount"), itemprop(scope, "ratingCount"))),
+			BestRating:  parseRatingNumber(itemprop(scope, "bestRating")),
+			WorstRating: parseRatingNumber(itemprop(scope, "worstRating")),
+			Source:      "microdata",
+		}
+		if v := parseRatingNumber(itemprop(scope, "ratingValue")); v > 0 {
+			r.Value = v
+			if item := scope.ParentsFiltered("[itemscope]").First(); item.Length() > 0 {
+				r.Item = itemprop(item, "name")
+			}
+			return r
+		}
+	}
+
+	return markupRating(doc)
+}
+
+// jsonLDRating converts an AggregateRating object, returning nil if it has
+// no numeric ratingValue.
+func jsonLDRating(obj map[string]any, item string) *AggregateRating {
+	value, err := strconv.ParseFloat(jsonLDString(obj, "ratingValue"), 64)
+	if err != nil {
+		return nil
+	}
+	return &AggregateRating{
+		Item:        item,
+		Value:       value,
+		Count:       parseCount(firstNonEmpty(jsonLDString(obj, "reviewCount"), jsonLDString(obj, "ratingCount"))),
+		BestRating:  parseRatingNumber(jsonLDString(obj, "bestRating")),
+		WorstRating: parseRatingNumber(jsonLDString(obj, "worstRating")),
+		Source:      "json-ld",
+	}
+}
+
+// markupRating reads the first star widget with a data-rating attribute or
+// a label like "4.5 out of 5", and a review count in its text or its parent's.
+func markupRating(doc *goquery.Document) *AggregateRating {
+	var r *AggregateRating
+	doc.Find(starSelector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		candidate := &AggregateRating{Source: "markup"}
+		if v, ok := s.Attr("data-rating"); ok {
+			candidate.Value = parseRatingNumber(v)
+		}
+		aria, _ := s.Attr("aria-label")
+		title, _ := s.Attr("title")
+		for _, text := range []string{aria, title, s.Text()} {
+			if m := ratingText.FindStringSubmatch(text); m != nil {
+				candidate.Value = parseRatingNumber(m[1])
+				candidate.BestRating = parseRatingNumber(m[2])
+				break
+			}
+		}
+		if candidate.Value <= 0 || (candidate.BestRating > 0 && candidate.Value > candidate.BestRating) {
+			return true
+		}
+		for _, text := range []string{s.Text(), s.Parent().Text()} {
+			if m := reviewCountText.FindStringSubmatch(text); m != nil {
+				candidate.Count = parseCount(m[1])
+				break
+			}
+		}
+		r = candidate
+		return false
+	})
+	return r
+}
+
+// parseRatingNumber parses a rating such as "4.5" or "4,5", returning 0 if
+// it is not a number.
+func parseRatingNumber(s string) float64 {
+	v, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseCount parses a count written with digit grouping, such as "1,234",
+// returning 0 if there is none.
+func parseCount(s string) int {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	n, _ := strconv.Atoi(digits)
+	return n
+}
+
+// String formats the rating for plain-text output.
+func (r AggregateRating) String() string {
+	s := strconv.FormatFloat(r.Value, 'f', -1, 64)
+	if r.BestRating > 0 {
+		s += "/" + strconv.FormatFloat(r.BestRating, 'f', -1, 64)
+	}
+	if r.Count > 0 {
+		s += fmt.Sprintf(" from %d reviews", r.Count)
+	}
+	if r.Item != "" {
+		s = r.Item + ": " + s
+	}
+	return s + " (from " + r.Source + ")"
+}
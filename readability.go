@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+const (
+	minParagraphLen = 25  // Shorter paragraphs are ignored as boilerplate
+	maxLinkDensity  = 0.3 // Paragraphs with more of their text in links are dropped
+)
+
+// linkDensity returns the fraction of the text in s that is inside links.
+func linkDensity(s *goquery.Selection, text string) float64 {
+	if len(text) == 0 {
+		return 0
+	}
+	linkLen := 0
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		linkLen += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLen) / float64(len(text))
+}
+
+// extractMainText returns the paragraphs of the page's main content. Each
+// substantial paragraph scores its parent and, at half weight, its
+// grandparent by length; the paragraphs inside the best-scoring container
+// that are long enough and not mostly links are kept.
+func extractMainText(doc *goquery.Document) []string {
+	scores := make(map[*html.Node]float64)
+	doc.Find("p").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if len(text) < minParagraphLen || linkDensity(s, text) > maxLinkDensity {
+			return
+		}
+		score := 1 + float64(len(text))/100
+		if score > 4 {
+			score = 4
+		}
+		parent := s.Parent()
+		if parent.Length() > 0 {
+			scores[parent.Get(0)] += score
+			if grand := parent.Parent(); grand.Length() > 0 {
+				scores[grand.Get(0)] += score / 2
+			}
+		}
+	})
+
+	var best *html.Node
+	for node, score := range scores {
+		if best == nil || score > scores[best] {
+			best = node
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	var texts []string
+	goquery.NewDocumentFromNode(best).Find("p").Each(func(i int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if len(text) >= minParagraphLen && linkDensity(s, text) <= maxLinkDensity {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Recipe is schema.org Recipe data found on the page.
+type Recipe struct {
+	Name         string            `json:"name,omitempty"`
+	Ingredients  []string          `json:"ingredients,omitempty"`
+	Instructions []string          `json:"instructions,omitempty"` // One entry per step, sections flattened
+	PrepTime     *RecipeTime       `json:"prep_time,omitempty"`
+	CookTime     *RecipeTime       `json:"cook_time,omitempty"`
+	TotalTime    *RecipeTime       `json:"total_time,omitempty"`
+	Yield        string            `json:"yield,omitempty"`
+	Nutrition    map[string]string `json:"nutrition,omitempty"` // NutritionInformation properties, e.g. calories
+	Source       string            `json:"source"`              // json-ld or microdata
+}
+
+// RecipeTime is a recipe duration as given in ISO 8601 and in seconds.
+type RecipeTime struct {
+	Duration string  `json:"duration"`
+	Seconds  float64 `json:"seconds,omitempty"`
+}
+
+// newRecipeTime parses an ISO 8601 duration, returning nil if s is empty.
+// Durations that don't parse keep only their text.
+func newRecipeTime(s string) *RecipeTime {
+	if s == "" {
+		return nil
+	}
+	t := &RecipeTime{Duration: s}
+	t.Seconds, _ = parseISODuration(s)
+	return t
+}
+
+// extractRecipe looks for recipe data in JSON-LD first, then microdata. It
+// returns nil if none is found.
+func extractRecipe(doc *goquery.Document) *Recipe {
+	if obj := findJSONLD(doc, "Recipe"); obj != nil {
+		r := &Recipe{
+			Name:         jsonLDString(obj, "name"),
+			Ingredients:  jsonLDTexts(firstPresent(obj, "recipeIngredient", "ingredients")),
+			Instructions: recipeSteps(obj["recipeInstructions"]),
+			PrepTime:     newRecipeTime(jsonLDString(obj, "prepTime")),
+			CookTime:     newRecipeTime(jsonLDString(obj, "cookTime")),
+			TotalTime:    newRecipeTime(jsonLDString(obj, "totalTime")),
+			Yield:        jsonLDString(obj, "recipeYield"),
+			Source:       "json-ld",
+		}
+		if nutrition := jsonLDObject(obj, "nutrition"); nutrition != nil {
+			r.Nutrition = make(map[string]string)
+			for key := range nutrition {
+				if v := jsonLDString(nutrition, key); v != "" && !strings.HasPrefix(key, "@") {
+					r.Nutrition[key] = v
+				}
+			}
+		}
+		return r
+	}
+
+	scope := microdataScope(doc, "Recipe")
+	if scope.Length() == 0 {
+		return nil
+	}
+	r := &Recipe{
+		Name:        itemprop(scope, "name"),
+		Ingredients: collapseAll(firstNonEmptyList(itemprops(scope, "recipeIngredient"), itemprops(scope, "ingredients"))),
+		PrepTime:    newRecipeTime(itemprop(scope, "prepTime")),
+		CookTime:    newRecipeTime(itemprop(scope, "cookTime")),
+		TotalTime:   newRecipeTime(itemprop(scope, "totalTime")),
+		Yield:       itemprop(scope, "recipeYield"),
+		Source:      "microdata",
+	}
+	// Steps are either HowToStep scopes or one block of text
+	scope.Find(`[itemprop~="recipeInstructions"]`).Each(func(i int, s *goquery.Selection) {
+		if steps := s.Find(`[itemprop~="text"]`); steps.Length() > 0 {
+			steps.Each(func(i int, step *goquery.Selection) {
+				r.Instructions = append(r.Instructions, collapseAll([]string{itempropValue(step)})...)
+			})
+		} else if items := s.Find("li"); items.Length() > 0 {
+			items.Each(func(i int, li *goquery.Selection) {
+				r.Instructions = append(r.Instructions, collapseAll([]string{li.Text()})...)
+			})
+		} else {
+			r.Instructions = append(r.Instructions, splitSteps(itempropValue(s))...)
+		}
+	})
+	if nutrition := scope.Find(`[itemprop~="nutrition"]`).First(); nutrition.Length() > 0 {
+		r.Nutrition = make(map[string]string)
+		nutrition.Find("[itemprop]").Each(func(i int, s *goquery.Selection) {
+			prop, _ := s.Attr("itemprop")
+			if v := strings.Join(strings.Fields(itempropValue(s)), " "); v != "" {
+				r.Nutrition[prop] = v
+			}
+		})
+	}
+	return r
+}
+
+// firstPresent returns the value of the first key set in obj.
+func firstPresent(obj map[string]any, keys ...string) any {
+	for _, key := range keys {
+		if v, ok := obj[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// firstNonEmptyList returns the first of lists that is not empty.
+func firstNonEmptyList(lists ...[]string) []string {
+	for _, l := range lists {
+		if len(l) > 0 {
+			return l
+		}
+	}
+	return nil
+}
+
+// collapseAll collapses the whitespace of each value, dropping empty ones.
+func collapseAll(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.Join(strings.Fields(v), " "); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// jsonLDTexts returns a JSON-LD value as a list of strings.
+func jsonLDTexts(v any) []string {
+	if list, ok := v.([]any); ok {
+		var texts []string
+		for _, item := range list {
+			texts = append(texts, jsonLDValue(item))
+		}
+		return collapseAll(texts)
+	}
+	return collapseAll([]string{jsonLDValue(v)})
+}
+
+// recipeSteps flattens recipeInstructions, which may be text, a list of
+// texts, HowToStep objects or HowToSection objects holding steps.
+func recipeSteps(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return splitSteps(t)
+	case []any:
+		var steps []string
+		for _, item := range t {
+			steps = append(steps, recipeSteps(item)...)
+		}
+		return steps
+	case map[string]any:
+		if items, ok := t["itemListElement"]; ok {
+			return recipeSteps(items)
+		}
+		return collapseAll([]string{firstNonEmpty(jsonLDString(t, "text"), jsonLDString(t, "name"))})
+	}
+	return nil
+}
+
+// splitSteps splits instructions given as one text into its lines.
+func splitSteps(s string) []string {
+	return collapseAll(strings.Split(s, "\n"))
+}
+
+// String formats the recipe for plain-text output.
+func (r Recipe) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (from %s)\n", firstNonEmpty(r.Name, "(unnamed)"), r.Source)
+	for _, t := range []struct {
+		label string
+		time  *RecipeTime
+	}{{"Prep time", r.PrepTime}, {"Cook time", r.CookTime}, {"Total time", r.TotalTime}} {
+		if t.time != nil {
+			fmt.Fprintf(&b, "%s: %s\n", t.label, t.time)
+		}
+	}
+	if r.Yield != "" {
+		fmt.Fprintf(&b, "Yield: %s\n", r.Yield)
+	}
+	if len(r.Ingredients) > 0 {
+		fmt.Fprintln(&b, "Ingredients:")
+		for _, ing := range r.Ingredients {
+			fmt.Fprintf(&b, "- %s\n", ing)
+		}
+	}
+	if len(r.Instructions) > 0 {
+		fmt.Fprintln(&b, "Instructions:")
+		for i, step := range r.Instructions {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+		}
+	}
+	if len(r.Nutrition) > 0 {
+		var parts []string
+		for _, key := range sortedKeys(r.Nutrition) {
+			parts = append(parts, key+": "+r.Nutrition[key])
+		}
+		fmt.Fprintf(&b, "Nutrition: %s\n", strings.Join(parts, ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// String formats the duration, e.g. "1h30m0s (PT1H30M)".
+func (t RecipeTime) String() string {
+	if t.Seconds == 0 {
+		return t.Duration
+	}
+	return fmt.Sprintf("%s (%s)", time.Duration(t.Seconds*float64(time.Second)), t.Duration)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretHeaders are the request headers redacted from the request log unless
+// -log-secrets is set.
+var secretHeaders = map[string]bool{
+	"Authorization":        true,
+	"Proxy-Authorization":  true,
+	"Cookie":               true,
+	"X-Api-Key":            true,
+	"X-Auth-Token":         true,
+	"X-Amz-Security-Token": true,
+}
+
+// requestLogger writes every request sent during the run to a file.
+type requestLogger struct {
+	mu      sync.Mutex
+	file    *os.File
+	secrets bool
+}
+
+// requestLog is the log written with -log-requests; nil logs nothing.
+var requestLog *requestLogger
+
+// newRequestLogger opens path for the request log. It returns nil if path is
+// empty.
+func newRequestLogger(path string, secrets bool) (*requestLogger, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening request log: %v", err)
+	}
+	return &requestLogger{file: file, secrets: secrets}, nil
+}
+
+// log records req's method, URL and headers, sorted by name so the same
+// request always logs the same way.
+func (l *requestLogger) log(req *http.Request) {
+	var b strings.Builder
+	u := req.URL.String()
+	if !l.secrets {
+		u = req.URL.Redacted()
+	}
+	fmt.Fprintf(&b, "%s %s %s\n", time.Now().Format(time.RFC3339), req.Method, u)
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if secretHeaders[name] && !l.secrets {
+				value = "[redacted]"
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", name, value)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.file.WriteString(b.String())
+}
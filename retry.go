@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retryBudget caps the number of retries made during the whole run, so a
+// widely failing site cannot multiply the requests by -retries.
+type retryBudget struct {
+	mu        sync.Mutex
+	limit     int
+	used      int
+	exhausted bool // A retry has been refused
+}
+
+// totalRetries is the -max-total-retries budget shared by every page; nil
+// leaves retries limited only by -retries.
+var totalRetries *retryBudget
+
+// newRetryBudget returns a budget of limit retries, or nil if limit is not
+// positive.
+func newRetryBudget(limit int) *retryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &retryBudget{limit: limit}
+}
+
+// take reports whether another retry may be made, and if so counts it.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.limit {
+		if !b.exhausted {
+			log.Printf("-max-total-retries budget of %d exhausted, failures are no longer retried", b.limit)
+			b.exhausted = true
+		}
+		return false
+	}
+	b.used++
+	return true
+}
+
+// String describes how much of the budget was consumed.
+func (b *retryBudget) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return fmt.Sprintf("%d of %d retries used", b.used, b.limit)
+}
+
+// retryDelay returns the backoff before retry number attempt (from 0),
+// doubling from minBackoffDelay up to maxBackoffDelay.
+func retryDelay(attempt int) time.Duration {
+	delay := minBackoffDelay << attempt
+	if delay <= 0 || delay > maxBackoffDelay {
+		delay = maxBackoffDelay
+	}
+	return delay
+}
+
+// truncationReader reports read errors as the end of the body, so that the
+// part that was read can still be parsed, and keeps the error in err.
+type truncationReader struct {
+	r   io.Reader
+	err error
+}
+
+func (t *truncationReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && err != io.EOF {
+		t.err = err
+		return n, io.EOF
+	}
+	return n, err
+}
+
+// bufferedBody is a response body read into memory. Once the bytes are
+// consumed it returns the error that cut the read short, if any, so that
+// truncationReader still sees a dropped connection.
+type bufferedBody struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (b *bufferedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF && b.err != nil {
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *bufferedBody) Close() error { return nil }
+
+// bufferBody reads resp.Body into memory and replaces it with a
+// bufferedBody. It returns the bytes read and the error that stopped the
+// read, if the body was cut short.
+func bufferBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = &bufferedBody{r: bytes.NewReader(body), err: err}
+	return body, err
+}
+
+// fetchPageRetrying is fetchPage with soft-failure retries: when
+// opts.RetryBodyMatch is set and a 200 response body matches it, such as a
+// captcha or "enable JavaScript" interstitial, the request is retried with
+// backoff up to opts.Retries times, as long as the -max-total-retries
+// budget allows. Each retry builds a new request, so it
+// picks the next -user-agent-file agent, and bypasses the -use-cache cache.
+// The returned body is buffered in memory, with any read error kept.
+func fetchPageRetrying(ctx context.Context, url string, opts Options) (*http.Response, error) {
+	if opts.RetryBodyMatch == nil {
+		return fetchPage(ctx, url, opts)
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := fetchPage(ctx, url, opts)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return resp, err
+		}
+		body, err := bufferBody(resp)
+		if err != nil {
+			// Cut short: scrapePageTo retries it or keeps what was read
+			return resp, nil
+		}
+		if !opts.RetryBodyMatch.Match(body) {
+			return resp, nil
+		}
+		if attempt >= opts.Retries {
+			return nil, fmt.Errorf("response body still matches -retry-if-body-matches after %d retries", attempt)
+		}
+		if !totalRetries.take() {
+			return nil, fmt.Errorf("response body matches -retry-if-body-matches and the -max-total-retries budget is exhausted")
+		}
+
+		delay := retryDelay(attempt)
+		log.Printf("%s: response body matches -retry-if-body-matches, retrying in %s", url, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error fetching URL: %v", ctx.Err())
+		}
+		opts.refetch = true
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsGroup is the rules listed under one or more User-agent lines.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRules is the group of a robots.txt file that applies to us.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsCache holds the parsed robots.txt of each host seen during the run.
+var robotsCache = struct {
+	sync.Mutex
+	m map[string]*robotsRules
+}{m: make(map[string]*robotsRules)}
+
+// parseRobots parses a robots.txt file and returns the group for agent,
+// falling back to the "*" group. An empty agent matches only "*".
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	var groups []*robotsGroup
+	var cur *robotsGroup
+	inAgents := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				cur = &robotsGroup{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			if cur != nil && value != "" {
+				cur.rules = append(cur.rules, robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)})
+			}
+		case "crawl-delay":
+			if cur != nil {
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					cur.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+		inAgents = false
+	}
+
+	agent = strings.ToLower(agent)
+	var match, star *robotsGroup
+	for _, g := range groups {
+		for _, a := range g.agents {
+			switch {
+			case a == "*":
+				if star == nil {
+					star = g
+				}
+			case agent != "" && strings.Contains(agent, a):
+				if match == nil {
+					match = g
+				}
+			}
+		}
+	}
+	if match == nil {
+		match = star
+	}
+	if match == nil {
+		return &robotsRules{}
+	}
+	return &robotsRules{rules: match.rules, crawlDelay: match.crawlDelay}
+}
+
+// allowed reports whether path may be fetched. The longest matching rule
+// wins, with Allow winning ties.
+func (r *robotsRules) allowed(path string) bool {
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allow = n, rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsPattern compiles a robots.txt path pattern, which may contain "*"
+// wildcards and end in "$" to anchor it to the end of the path.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	expr := regexp.QuoteMeta(strings.TrimSuffix(pattern, "$"))
+	expr = "^" + strings.ReplaceAll(expr, `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// robotsFor returns the robots.txt rules for u's host, fetching them on first
+// use. A missing or unreadable robots.txt allows everything.
+func robotsFor(ctx context.Context, u *url.URL, opts Options) *robotsRules {
+	robotsCache.Lock()
+	rules, ok := robotsCache.m[u.Host]
+	robotsCache.Unlock()
+	if ok {
+		return rules
+	}
+
+	rules = &robotsRules{}
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err == nil {
+		if userAgents != nil {
+			req.Header.Set("User-Agent", userAgents.pick())
+		}
+		var resp *http.Response
+		if resp, err = doRequest(req); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				rules = parseRobots(resp.Body, opts.UserAgent)
+			}
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to fetch %s: %v", robotsURL, err)
+	}
+
+	robotsCache.Lock()
+	robotsCache.m[u.Host] = rules
+	robotsCache.Unlock()
+	return rules
+}
+
+// robotsAllowed reports whether rawURL may be crawled under its host's
+// robots.txt.
+func robotsAllowed(ctx context.Context, rawURL string, opts Options) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return robotsFor(ctx, u, opts).allowed(path)
+}
+
+// hostDelay returns the minimum delay between requests to host: -delay, or
+// the host's robots.txt Crawl-delay when that is larger and robots.txt is
+// being honored.
+func hostDelay(host string, opts Options) time.Duration {
+	if !opts.RespectRobots {
+		return opts.Delay
+	}
+	robotsCache.Lock()
+	rules := robotsCache.m[host]
+	robotsCache.Unlock()
+	if rules != nil && rules.crawlDelay > opts.Delay {
+		return rules.crawlDelay
+	}
+	return opts.Delay
+}
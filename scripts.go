@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractThirdPartyScripts counts the external <script src> URLs by host,
+// for every host other than the page's own. When page is nil every host is
+// counted.
+func extractThirdPartyScripts(doc *goquery.Document, page, base *url.URL) map[string]int {
+	pageHost := ""
+	if page != nil {
+		pageHost = strings.ToLower(page.Hostname())
+	}
+	hosts := make(map[string]int)
+	doc.Find("script[src]").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		abs, ok := resolveURL(base, src)
+		if !ok {
+			return
+		}
+		u, err := url.Parse(abs)
+		if err != nil || u.Hostname() == "" {
+			return
+		}
+		host := strings.ToLower(u.Hostname())
+		if host != pageHost {
+			hosts[host]++
+		}
+	})
+	if len(hosts) == 0 {
+		return nil
+	}
+	return hosts
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// loadSeen reads the links recorded by earlier runs, one per line. A missing
+// file is the first run: nothing has been seen yet.
+func loadSeen(path string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return seen, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading seen file: %v", err)
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			seen[line] = true
+		}
+	}
+	return seen, nil
+}
+
+// saveSeen writes the seen links to path, sorted, replacing the file
+// atomically.
+func saveSeen(path string, seen map[string]bool) error {
+	links := make([]string, 0, len(seen))
+	for link := range seen {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(links, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("error writing seen file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing seen file: %v", err)
+	}
+	return nil
+}
+
+// keepNewLinks removes the links in seen from data, adds the rest to seen,
+// and returns how many were new.
+func keepNewLinks(data *ScrapeData, seen map[string]bool) int {
+	var fresh []Link
+	for _, link := range data.Links {
+		if !seen[link.URL] {
+			seen[link.URL] = true
+			fresh = append(fresh, link)
+		}
+	}
+	data.Links = fresh
+	return len(fresh)
+}
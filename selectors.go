@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// selectRule is a named extraction rule given with -select.
+type selectRule struct {
+	Name     string
+	Selector string
+	Attr     string // Attribute to collect instead of the text, if set
+}
+
+// parseSelectRule parses a rule of the form name=selector[@attr].
+func parseSelectRule(s string) (selectRule, error) {
+	name, selector, ok := strings.Cut(s, "=")
+	name, selector = strings.TrimSpace(name), strings.TrimSpace(selector)
+	if !ok || name == "" || selector == "" {
+		return selectRule{}, fmt.Errorf("invalid -select %q, want name=selector[@attr]", s)
+	}
+	rule := selectRule{Name: name, Selector: selector}
+	if i := attrSeparator(selector); i > 0 {
+		rule.Selector = strings.TrimSpace(selector[:i])
+		rule.Attr = strings.TrimSpace(selector[i+1:])
+	}
+	return rule, nil
+}
+
+// attrSeparator returns the index of the '@' that starts the @attr suffix
+// of a -select selector, or -1 if it has none. An '@' inside quotes or
+// brackets, as in a[href^="mailto:x@y"], is part of the selector.
+func attrSeparator(selector string) int {
+	at, depth := -1, 0
+	var quote rune
+	for i, r := range selector {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+		case r == '@' && depth == 0:
+			at = i
+		}
+	}
+	if at < 0 || strings.ContainsAny(selector[at+1:], "[]()\"' >+~,") {
+		return -1
+	}
+	return at
+}
+
+// extractSelections applies each rule to the document, collecting the
+// trimmed text (or attribute) of every match under the rule's name.
+func extractSelections(doc *goquery.Document, rules []selectRule) map[string][]string {
+	result := make(map[string][]string)
+	for _, rule := range rules {
+		doc.Find(rule.Selector).Each(func(i int, s *goquery.Selection) {
+			var value string
+			if rule.Attr != "" {
+				v, ok := s.Attr(rule.Attr)
+				if !ok {
+					return
+				}
+				value = strings.TrimSpace(v)
+			} else {
+				value = strings.TrimSpace(s.Text())
+			}
+			if value != "" {
+				result[rule.Name] = append(result[rule.Name], value)
+			}
+		})
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// shutdownGrace is how long in-flight requests may keep running after an
+// interrupt before they are cancelled.
+const shutdownGrace = 5 * time.Second
+
+// errInterrupted is returned by crawl when it was stopped by a signal. The
+// data gathered up to that point is returned alongside it.
+var errInterrupted = errors.New("interrupted")
+
+// withGrace returns a context that is cancelled shutdownGrace after parent
+// is done, so in-flight work gets a chance to finish.
+func withGrace(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	go func() {
+		select {
+		case <-parent.Done():
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-time.After(shutdownGrace):
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// statusRange is an inclusive range of HTTP status codes.
+type statusRange struct{ From, To int }
+
+// parseStatusRanges parses a -accept-status list such as "301-302,404".
+func parseStatusRanges(s string) ([]statusRange, error) {
+	var ranges []statusRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(first))
+		to, err2 := strconv.Atoi(strings.TrimSpace(last))
+		if err1 != nil || err2 != nil || from < 100 || to > 599 || to < from {
+			return nil, fmt.Errorf("invalid -accept-status %q, want codes or ranges like 301-302,404", part)
+		}
+		ranges = append(ranges, statusRange{from, to})
+	}
+	return ranges, nil
+}
+
+// acceptStatus reports whether a response with the given status is parsed:
+// 200 OK, and any of the codes listed with -accept-status.
+func acceptStatus(code int, opts Options) bool {
+	if code == http.StatusOK {
+		return true
+	}
+	for _, r := range opts.AcceptStatus {
+		if code >= r.From && code <= r.To {
+			return true
+		}
+	}
+	return false
+}
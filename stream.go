@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// streamFlushInterval is how many items are buffered between flushes when
+// streaming output.
+const streamFlushInterval = 100
+
+// itemWriter receives each extracted item as soon as it is found. kind is
+// "link", "text" or "image".
+type itemWriter interface {
+	WriteItem(kind string, value any) error
+}
+
+// WriteItem collects the item into the matching ScrapeData list.
+func (d *ScrapeData) WriteItem(kind string, value any) error {
+	switch v := value.(type) {
+	case Link:
+		d.Links = append(d.Links, v)
+	case string:
+		if kind == "image" {
+			d.Images = append(d.Images, v)
+		}
+	case TextEntry:
+		d.Texts = append(d.Texts, v)
+	case InlineImage:
+		d.InlineImages = append(d.InlineImages, v)
+	}
+	return nil
+}
+
+// textItemWriter writes one "kind: value" line per item, flushing
+// periodically so memory use stays flat on very large pages.
+type textItemWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func (t *textItemWriter) WriteItem(kind string, value any) error {
+	if _, err := fmt.Fprintf(t.w, "%s: %v\n", kind, value); err != nil {
+		return err
+	}
+	t.n++
+	if t.n%streamFlushInterval == 0 {
+		return t.w.Flush()
+	}
+	return nil
+}
+
+// jsonItemWriter writes one {"type": kind, "value": value} JSON object per
+// line for each item, flushing periodically like textItemWriter.
+type jsonItemWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+	n   int
+}
+
+func newJSONItemWriter(w io.Writer) *jsonItemWriter {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &jsonItemWriter{w: bw, enc: enc}
+}
+
+func (j *jsonItemWriter) WriteItem(kind string, value any) error {
+	if err := j.enc.Encode(jsonItem{Type: kind, Value: value}); err != nil {
+		return err
+	}
+	j.n++
+	if j.n%streamFlushInterval == 0 {
+		return j.w.Flush()
+	}
+	return nil
+}
+
+// jsonItem is one line of jsonl output.
+type jsonItem struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// writeJSONItems writes data as jsonl: a line per link, text and image,
+// followed by the other fields.
+func writeJSONItems(w io.Writer, data ScrapeData) error {
+	j := newJSONItemWriter(w)
+	for _, link := range data.Links {
+		if err := j.WriteItem("link", link); err != nil {
+			return err
+		}
+	}
+	for _, text := range data.Texts {
+		if err := j.WriteItem("text", text); err != nil {
+			return err
+		}
+	}
+	for _, src := range data.Images {
+		if err := j.WriteItem("image", src); err != nil {
+			return err
+		}
+	}
+	for _, img := range data.InlineImages {
+		if err := j.WriteItem("image", img); err != nil {
+			return err
+		}
+	}
+	if err := j.writeFields(data); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// itemKeys are the ScrapeData JSON keys written as one line per item.
+var itemKeys = map[string]bool{"links": true, "texts": true, "images": true, "inline_images": true}
+
+// writeFields writes a line for each field of data other than the items,
+// such as the title, metadata and errors, with the field's JSON key as the
+// type. Empty fields are left out.
+func (j *jsonItemWriter) writeFields(data ScrapeData) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var values, zero map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+	// Fields such as scraped_at that are written even when unset
+	raw, _ = json.Marshal(ScrapeData{})
+	json.Unmarshal(raw, &zero)
+	for _, key := range scrapeDataKeys() {
+		value, ok := values[key]
+		if !ok || itemKeys[key] || bytes.Equal(value, zero[key]) {
+			continue
+		}
+		if err := j.WriteItem(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamToFile scrapes url, writing each item to filename as it is found,
+// as jsonl lines for the jsonl format, followed by the other fields, or as
+// "kind: value" lines for the text format. A filename of "-" writes to
+// standard output.
+func streamToFile(ctx context.Context, url string, opts Options, filename, format string) error {
+	if format != "text" && format != "jsonl" {
+		return fmt.Errorf("-stream-output needs the text or jsonl format, not %s", format)
+	}
+	out := os.Stdout
+	if filename != "-" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("error creating file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var w itemWriter
+	var j *jsonItemWriter
+	var flush func() error
+	if format == "jsonl" {
+		j = newJSONItemWriter(out)
+		w, flush = j, j.w.Flush
+	} else {
+		t := &textItemWriter{w: bufio.NewWriter(out)}
+		w, flush = t, t.w.Flush
+	}
+	data, err := scrapePageTo(ctx, url, opts, w)
+	if err == nil && j != nil {
+		// The items went out as they were found; the rest follows them
+		err = j.writeFields(data)
+	}
+	if ferr := flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Table is a <table> on the page. Rows is the raw grid of cell texts,
+// including any header row. When the table has a header row, Records maps
+// each data row by header text; header-less tables only have the grid.
+type Table struct {
+	Caption string              `json:"caption,omitempty"`
+	Headers []string            `json:"headers,omitempty"`
+	Rows    [][]string          `json:"rows"`
+	Records []map[string]string `json:"records,omitempty"`
+}
+
+// extractTables collects every table on the page. Rows of nested tables
+// belong only to the innermost table.
+func extractTables(doc *goquery.Document) []Table {
+	var tables []Table
+	doc.Find("table").Each(func(i int, tbl *goquery.Selection) {
+		t := Table{Caption: strings.TrimSpace(tbl.ChildrenFiltered("caption").First().Text())}
+		headerRow := -1
+		rows := tbl.ChildrenFiltered("thead, tbody, tfoot").ChildrenFiltered("tr").AddSelection(tbl.ChildrenFiltered("tr"))
+		rows.Each(func(j int, tr *goquery.Selection) {
+			cells := tr.ChildrenFiltered("th, td")
+			var row []string
+			cells.Each(func(k int, cell *goquery.Selection) {
+				row = append(row, strings.Join(strings.Fields(cell.Text()), " "))
+			})
+			if len(row) == 0 {
+				return
+			}
+			// The header row is a <thead> row or a first row of only <th>
+			if headerRow < 0 && len(t.Rows) == 0 &&
+				(goquery.NodeName(tr.Parent()) == "thead" || cells.Filter("td").Length() == 0) {
+				headerRow = len(t.Rows)
+			}
+			t.Rows = append(t.Rows, row)
+		})
+		if len(t.Rows) == 0 {
+			return
+		}
+		if headerRow >= 0 {
+			t.Headers = tableHeaders(t.Rows[headerRow])
+			for _, row := range t.Rows[headerRow+1:] {
+				record := make(map[string]string, len(row))
+				for k, cell := range row {
+					if k < len(t.Headers) {
+						record[t.Headers[k]] = cell
+					} else {
+						record[fmt.Sprintf("column %d", k+1)] = cell
+					}
+				}
+				t.Records = append(t.Records, record)
+			}
+		}
+		tables = append(tables, t)
+	})
+	return tables
+}
+
+// tableHeaders turns header cells into unique record keys, naming empty
+// cells by their column and numbering repeated names.
+func tableHeaders(cells []string) []string {
+	headers := make([]string, len(cells))
+	seen := make(map[string]int)
+	for i, h := range cells {
+		if h == "" {
+			h = fmt.Sprintf("column %d", i+1)
+		}
+		if seen[h]++; seen[h] > 1 {
+			h = fmt.Sprintf("%s (%d)", h, seen[h])
+		}
+		headers[i] = h
+	}
+	return headers
+}
+
+// String formats the table for plain-text output.
+func (t Table) String() string {
+	var b strings.Builder
+	if t.Caption != "" {
+		b.WriteString(t.Caption + " ")
+	}
+	fmt.Fprintf(&b, "(%d rows)\n", len(t.Rows))
+	for _, row := range t.Rows {
+		b.WriteString("   | " + strings.Join(row, " | ") + " |\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
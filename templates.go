@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// EmbeddedContent is the markup inside a <template> or <noscript> element.
+type EmbeddedContent struct {
+	Tag  string `json:"tag"` // template or noscript
+	ID   string `json:"id,omitempty"`
+	HTML string `json:"html"`
+}
+
+// extractTemplates returns the inner markup of every <template> and
+// <noscript> element.
+//
+// The parser keeps <template> content as ordinary children, so the links,
+// texts and images in it are already extracted with the rest of the page.
+// <noscript> content is parsed as plain text because scripting is assumed to
+// be on; it is returned in noscript so the caller can parse it as HTML.
+func extractTemplates(doc *goquery.Document) (all []EmbeddedContent, noscript []string) {
+	doc.Find("template, noscript").Each(func(i int, s *goquery.Selection) {
+		tag := goquery.NodeName(s)
+		id, _ := s.Attr("id")
+		var inner string
+		if tag == "noscript" {
+			inner = s.Text()
+		} else {
+			inner, _ = s.Html()
+		}
+		inner = strings.TrimSpace(inner)
+		if inner == "" {
+			return
+		}
+		all = append(all, EmbeddedContent{Tag: tag, ID: id, HTML: inner})
+		if tag == "noscript" {
+			noscript = append(noscript, inner)
+		}
+	})
+	return all, noscript
+}
+
+// parseNoscript parses the content of <noscript> elements as HTML and merges
+// the links, texts, images and inline images found in it into data. Relative
+// URLs resolve against base like the rest of the page.
+func parseNoscript(contents []string, base *url.URL, data ScrapeData, opts Options, out itemWriter) (ScrapeData, error) {
+	innerOpts := Options{
+		WithPath:      opts.WithPath,
+		WithHTML:      opts.WithHTML,
+		StripTracking: opts.StripTracking,
+		LinkContext:   opts.LinkContext,
+	}
+	for _, content := range contents {
+		inner, err := parsePageTo(strings.NewReader(content), "text/html; charset=utf-8", base, innerOpts, out)
+		if err != nil {
+			return data, err
+		}
+		data.Links = append(data.Links, inner.Links...)
+		data.Texts = append(data.Texts, inner.Texts...)
+		data.Images = append(data.Images, inner.Images...)
+		data.InlineImages = append(data.InlineImages, inner.InlineImages...)
+	}
+	return data, nil
+}
+
+// idSuffix formats an element id as " #id" for plain-text output, or ""
+// when it has none.
+func idSuffix(id string) string {
+	if id == "" {
+		return ""
+	}
+	return " #" + id
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// TextEntry is a piece of text extracted from the page.
+type TextEntry struct {
+	Text string `json:"text"`
+	Path string `json:"path,omitempty"` // Source element path, with -with-path
+	Lang string `json:"lang,omitempty"` // Language from the nearest lang attribute
+	Dir  string `json:"dir,omitempty"`  // Direction from the nearest dir attribute
+	HTML string `json:"html,omitempty"` // Inner HTML of the element, with -with-html
+
+	WordCount int      `json:"word_count"`        // Number of whitespace-separated words in Text
+	Sources   []string `json:"sources,omitempty"` // Pages the text was found on, with -merge
+}
+
+// String formats the entry for plain-text output.
+func (t TextEntry) String() string {
+	line := t.Text
+	if t.Path != "" {
+		line = "[" + t.Path + "] " + line
+	}
+	if attrs := strings.TrimSpace(t.Lang + " " + t.Dir); attrs != "" {
+		line += " (" + attrs + ")"
+	}
+	return line
+}
+
+// inheritedAttr returns the value of attr on s or its nearest ancestor that
+// sets it.
+func inheritedAttr(s *goquery.Selection, attr string) string {
+	v, _ := s.Closest("[" + attr + "]").Attr(attr)
+	return strings.TrimSpace(v)
+}
+
+// elementPath returns a simple CSS-like path to the element in s, such as
+// "body > div.content > p".
+func elementPath(s *goquery.Selection) string {
+	var parts []string
+	for n := s; n.Length() > 0; n = n.Parent() {
+		tag := goquery.NodeName(n)
+		if tag == "html" || tag == "#document" {
+			break
+		}
+		part := tag
+		if id, ok := n.Attr("id"); ok && id != "" {
+			part += "#" + id
+		}
+		if class, ok := n.Attr("class"); ok {
+			for _, c := range strings.Fields(class) {
+				part += "." + c
+			}
+		}
+		parts = append(parts, part)
+	}
+	// Reverse so the path reads from the outermost element inwards.
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
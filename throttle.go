@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	minBackoffDelay = time.Second      // Delay used after the first 429/503 if none is set
+	maxBackoffDelay = 60 * time.Second // Upper bound on a host's delay
+)
+
+// hostState is the throttling state of a single host.
+type hostState struct {
+	delay time.Duration // Current delay between requests
+	last  time.Time     // When the last request was sent
+}
+
+// hostThrottle spaces out requests per host. Each host starts at the base
+// delay; the delay doubles when the host answers 429 or 503 and shrinks back
+// towards the base delay on successful responses.
+type hostThrottle struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// throttle is shared by every request made during the run.
+var throttle = &hostThrottle{hosts: make(map[string]*hostState)}
+
+// state returns the state for host, creating it at the base delay.
+func (t *hostThrottle) state(host string, base time.Duration) *hostState {
+	st, ok := t.hosts[host]
+	if !ok {
+		st = &hostState{delay: base}
+		t.hosts[host] = st
+	}
+	return st
+}
+
+// wait blocks until a request to host may be sent, or until ctx is done,
+// returning ctx's error.
+func (t *hostThrottle) wait(ctx context.Context, host string, base time.Duration) error {
+	t.mu.Lock()
+	st := t.state(host, base)
+	next := st.last.Add(st.delay)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	st.last = next
+	t.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record adjusts the host's delay according to the response.
+func (t *hostThrottle) record(host string, base time.Duration, resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.state(host, base)
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		delay := st.delay * 2
+		if delay < minBackoffDelay {
+			delay = minBackoffDelay
+		}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			if ra := time.Duration(secs) * time.Second; ra > delay {
+				delay = ra
+			}
+		}
+		if delay > maxBackoffDelay {
+			delay = maxBackoffDelay
+		}
+		if delay != st.delay {
+			log.Printf("%s answered %d, slowing down to %s between requests", host, resp.StatusCode, delay)
+		}
+		st.delay = delay
+		return
+	}
+
+	if st.delay > base {
+		st.delay -= (st.delay - base) / 4
+		if st.delay-base < 10*time.Millisecond {
+			st.delay = base
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// tokenPool rotates bearer tokens across requests. Tokens that were answered
+// with 401 or 429 collect strikes and are only used again once every other
+// token has at least as many.
+type tokenPool struct {
+	mu      sync.Mutex
+	tokens  []string
+	strikes []int
+	next    int
+}
+
+// bearerTokens is the pool used for page requests; nil sends no token.
+var bearerTokens *tokenPool
+
+// newTokenPool loads the tokens in path. It returns nil if path is empty.
+func newTokenPool(path string) (*tokenPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	tokens, err := loadLines(path, "tokens")
+	if err != nil {
+		return nil, err
+	}
+	return &tokenPool{tokens: tokens, strikes: make([]int, len(tokens))}, nil
+}
+
+// pick returns the token for the next request: the next one in turn among
+// those with the fewest strikes.
+func (p *tokenPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best := -1
+	for i := range p.tokens {
+		j := (p.next + i) % len(p.tokens)
+		if best < 0 || p.strikes[j] < p.strikes[best] {
+			best = j
+		}
+	}
+	p.next = (best + 1) % len(p.tokens)
+	return p.tokens[best]
+}
+
+// record gives the token sent with req a strike if the server rejected or
+// rate-limited it.
+func (p *tokenPool) record(req *http.Request, status int) {
+	if status != http.StatusUnauthorized && status != http.StatusTooManyRequests {
+		return
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, t := range p.tokens {
+		if t == token {
+			p.strikes[i]++
+			log.Printf("Token %d of %d answered %d, deprioritizing it", i+1, len(p.tokens), status)
+			return
+		}
+	}
+}
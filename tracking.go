@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are the query parameters removed by -strip-tracking:
+// analytics campaign tags and ad-click identifiers. Names ending in * are
+// prefixes. Matching ignores case.
+var trackingParams = []string{
+	"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid",
+	"msclkid", "yclid", "twclid", "ttclid", "igshid", "li_fat_id",
+	"mc_eid", "mc_cid", "mkt_tok", "_hsenc", "_hsmi", "__hssc", "__hstc",
+	"__hsfp", "hsctatracking", "_ga", "_gl", "oly_anon_id", "oly_enc_id",
+	"vero_id", "vero_conv", "wickedid", "spm", "scid",
+}
+
+// isTrackingParam reports whether a query parameter name is in
+// trackingParams.
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range trackingParams {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTracking removes tracking parameters from the query of rawURL. The
+// other parameters keep their order and encoding; URLs that do not parse
+// are returned unchanged.
+func stripTracking(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	var kept []string
+	for _, part := range strings.Split(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(part, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if part != "" && !isTrackingParam(name) {
+			kept = append(kept, part)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+)
+
+// uaRotator hands out user agents round-robin or at random.
+type uaRotator struct {
+	mu     sync.Mutex
+	agents []string
+	next   int
+	random bool
+}
+
+// userAgents is the rotation used by every request; nil keeps Go's default
+// user agent.
+var userAgents *uaRotator
+
+// loadLines reads one entry per line from a file of the given kind,
+// skipping blank lines and lines starting with #.
+func loadLines(path, kind string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s file: %v", kind, err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s file: %v", kind, err)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s file %s is empty", kind, path)
+	}
+	return lines, nil
+}
+
+// newUARotator builds the rotation from -user-agent and -user-agent-file.
+// It returns nil if neither is set.
+func newUARotator(opts Options) (*uaRotator, error) {
+	var agents []string
+	if opts.UserAgent != "" {
+		agents = append(agents, opts.UserAgent)
+	}
+	if opts.UserAgentFile != "" {
+		loaded, err := loadLines(opts.UserAgentFile, "user agent")
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, loaded...)
+	}
+	if len(agents) == 0 {
+		return nil, nil
+	}
+	switch opts.UserAgentRotation {
+	case "", "round-robin":
+		return &uaRotator{agents: agents}, nil
+	case "random":
+		return &uaRotator{agents: agents, random: true}, nil
+	}
+	return nil, fmt.Errorf("unknown -user-agent-rotation %q, want round-robin or random", opts.UserAgentRotation)
+}
+
+// pick returns the user agent for the next request.
+func (r *uaRotator) pick() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.random {
+		return r.agents[rand.Intn(len(r.agents))]
+	}
+	ua := r.agents[r.next]
+	r.next = (r.next + 1) % len(r.agents)
+	return ua
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Video is a schema.org VideoObject, or the video described by og:video
+// tags.
+type Video struct {
+	Name            string     `json:"name,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	Thumbnail       string     `json:"thumbnail,omitempty"`
+	Duration        string     `json:"duration,omitempty"`         // As given, e.g. PT1M30S
+	DurationSeconds float64    `json:"duration_seconds,omitempty"` // Duration parsed to seconds
+	UploadDate      *time.Time `json:"upload_date,omitempty"`
+	ContentURL      string     `json:"content_url,omitempty"`
+	EmbedURL        string     `json:"embed_url,omitempty"`
+	Type            string     `json:"type,omitempty"` // MIME type, from og:video:type
+	Width           int        `json:"width,omitempty"`
+	Height          int        `json:"height,omitempty"`
+	Source          string     `json:"source"` // json-ld or og
+}
+
+// isoDuration matches ISO 8601 durations without years or months, whose
+// length in seconds depends on the calendar.
+var isoDuration = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration converts an ISO 8601 duration such as PT1H2M3.5S to
+// seconds.
+func parseISODuration(s string) (float64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", s)
+	}
+	units := []float64{7 * 24 * 3600, 24 * 3600, 3600, 60, 1}
+	total := 0.0
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", s)
+		}
+		total += n * unit
+	}
+	return total, nil
+}
+
+// extractVideos returns the VideoObjects in the page's JSON-LD, including
+// those nested under another object's video property. Without any, the
+// og:video tags are used.
+func extractVideos(doc *goquery.Document, base *url.URL) []Video {
+	var videos []Video
+	for _, obj := range jsonLDObjects(doc) {
+		candidates := append([]map[string]any{obj}, jsonLDObjectList(obj, "video")...)
+		for _, v := range candidates {
+			if jsonLDHasType(v, "VideoObject") {
+				videos = append(videos, jsonLDVideo(v, base))
+			}
+		}
+	}
+	if len(videos) > 0 {
+		return videos
+	}
+	if v, ok := openGraphVideo(doc, base); ok {
+		videos = append(videos, v)
+	}
+	return videos
+}
+
+// jsonLDVideo converts a VideoObject.
+func jsonLDVideo(obj map[string]any, base *url.URL) Video {
+	v := Video{
+		Name:        jsonLDString(obj, "name"),
+		Description: jsonLDString(obj, "description"),
+		Duration:    jsonLDString(obj, "duration"),
+		Width:       atoiOrZero(jsonLDString(obj, "width")),
+		Height:      atoiOrZero(jsonLDString(obj, "height")),
+		Type:        jsonLDString(obj, "encodingFormat"),
+		Source:      "json-ld",
+	}
+	v.Thumbnail, _ = resolveURL(base, firstNonEmpty(jsonLDString(obj, "thumbnailUrl"), jsonLDString(obj, "thumbnail")))
+	v.ContentURL, _ = resolveURL(base, jsonLDString(obj, "contentUrl"))
+	v.EmbedURL, _ = resolveURL(base, jsonLDString(obj, "embedUrl"))
+	if t, ok := parseTimestamp(jsonLDString(obj, "uploadDate")); ok {
+		v.UploadDate = &t
+	}
+	if v.Duration != "" {
+		v.DurationSeconds, _ = parseISODuration(v.Duration)
+	}
+	return v
+}
+
+// openGraphVideo builds a Video from og:video tags, with the page's og:title,
+// og:description and og:image as its name, description and thumbnail. The
+// og:video:duration tag is in seconds.
+func openGraphVideo(doc *goquery.Document, base *url.URL) (Video, bool) {
+	src := firstNonEmpty(metaContent(doc, "og:video:secure_url"), metaContent(doc, "og:video:url"), metaContent(doc, "og:video"))
+	if src == "" {
+		return Video{}, false
+	}
+	v := Video{
+		Name:        metaContent(doc, "og:title"),
+		Description: metaContent(doc, "og:description"),
+		Type:        metaContent(doc, "og:video:type"),
+		Width:       atoiOrZero(metaContent(doc, "og:video:width")),
+		Height:      atoiOrZero(metaContent(doc, "og:video:height")),
+		Source:      "og",
+	}
+	// Players are embedded; files are linked directly
+	abs, _ := resolveURL(base, src)
+	if strings.HasPrefix(v.Type, "video/") {
+		v.ContentURL = abs
+	} else {
+		v.EmbedURL = abs
+	}
+	v.Thumbnail, _ = resolveURL(base, metaContent(doc, "og:image"))
+	if secs := firstNonEmpty(metaContent(doc, "og:video:duration"), metaContent(doc, "video:duration")); secs != "" {
+		if n, err := strconv.ParseFloat(secs, 64); err == nil {
+			v.DurationSeconds = n
+		}
+	}
+	if t, ok := parseTimestamp(metaContent(doc, "video:release_date")); ok {
+		v.UploadDate = &t
+	}
+	return v, true
+}
+
+// atoiOrZero parses s as an integer, returning 0 if it is not one.
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// String formats the video for plain-text output.
+func (v Video) String() string {
+	parts := []string{firstNonEmpty(v.Name, "(untitled)")}
+	if v.DurationSeconds > 0 {
+		parts = append(parts, (time.Duration(v.DurationSeconds * float64(time.Second))).String())
+	}
+	if u := firstNonEmpty(v.ContentURL, v.EmbedURL); u != "" {
+		parts = append(parts, u)
+	}
+	return strings.Join(parts, " - ")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Viewport is the parsed content of <meta name="viewport">.
+type Viewport struct {
+	Content      string   `json:"content"`
+	Width        string   `json:"width,omitempty"` // device-width or a number of pixels
+	InitialScale *float64 `json:"initial_scale,omitempty"`
+	MinimumScale *float64 `json:"minimum_scale,omitempty"`
+	MaximumScale *float64 `json:"maximum_scale,omitempty"`
+	UserScalable *bool    `json:"user_scalable,omitempty"`
+	Responsive   bool     `json:"responsive"` // Width follows the device
+}
+
+// missingViewport is the warning for pages without a viewport meta tag,
+// which mobile browsers lay out at desktop width.
+const missingViewport = `no <meta name="viewport"> tag: mobile browsers will render the page at desktop width`
+
+// extractViewport parses the page's viewport meta tag, or returns nil if it
+// has none. Properties may be separated by commas or semicolons.
+func extractViewport(doc *goquery.Document) *Viewport {
+	s := doc.Find(`meta[name="viewport" i]`).First()
+	if s.Length() == 0 {
+		return nil
+	}
+	content, _ := s.Attr("content")
+	v := &Viewport{Content: strings.TrimSpace(content)}
+	for _, prop := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, _ := strings.Cut(prop, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		scale := func() *float64 {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil
+			}
+			return &f
+		}
+		switch key {
+		case "width":
+			v.Width = value
+		case "initial-scale":
+			v.InitialScale = scale()
+		case "minimum-scale":
+			v.MinimumScale = scale()
+		case "maximum-scale":
+			v.MaximumScale = scale()
+		case "user-scalable":
+			scalable := value == "yes" || value == "1"
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				scalable = f >= 1 || f <= -1
+			}
+			v.UserScalable = &scalable
+		}
+	}
+	// An initial-scale alone also makes browsers use the device width
+	v.Responsive = v.Width == "device-width" || (v.Width == "" && v.InitialScale != nil)
+	return v
+}
+
+// String formats the viewport for plain-text output.
+func (v *Viewport) String() string {
+	if v.Responsive {
+		return v.Content + " (responsive)"
+	}
+	return v.Content + " (not responsive)"
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ResourceWeight is the number and total size of a page's assets of one
+// type.
+type ResourceWeight struct {
+	Count int   `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// PageWeight estimates the transfer size of the assets a page references.
+type PageWeight struct {
+	Total   int64                     `json:"total"`
+	ByType  map[string]ResourceWeight `json:"by_type"`           // image, script or stylesheet
+	Unknown int                       `json:"unknown,omitempty"` // Assets whose size could not be determined
+}
+
+// weightCache holds the size found for each script and stylesheet URL during
+// the run; -1 means it could not be determined.
+var weightCache = struct {
+	sync.Mutex
+	m map[string]int64
+}{m: make(map[string]int64)}
+
+// extractAssets returns the absolute URLs of the scripts and stylesheets a
+// page loads, by type. Images are taken from ScrapeData.Images.
+func extractAssets(doc *goquery.Document, base *url.URL) map[string][]string {
+	assets := make(map[string][]string)
+	add := func(kind string, s *goquery.Selection, attr string) {
+		v, _ := s.Attr(attr)
+		if abs, ok := resolveURL(base, v); ok && strings.HasPrefix(abs, "http") {
+			assets[kind] = append(assets[kind], abs)
+		}
+	}
+	doc.Find("script[src]").Each(func(i int, s *goquery.Selection) { add("script", s, "src") })
+	doc.Find(`link[rel~="stylesheet"][href]`).Each(func(i int, s *goquery.Selection) { add("stylesheet", s, "href") })
+	return assets
+}
+
+// fetchAssetSize returns the size of the asset at u from the Content-Length
+// of a HEAD request. Servers that reject HEAD or omit the length are asked
+// for a single byte, whose Content-Range gives the total, and as a last
+// resort the body is read and counted.
+func fetchAssetSize(ctx context.Context, u string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	if userAgents != nil {
+		req.Header.Set("User-Agent", userAgents.pick())
+	}
+	resp, err := doRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && resp.ContentLength >= 0 {
+		return resp.ContentLength, nil
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	if userAgents != nil {
+		req.Header.Set("User-Agent", userAgents.pick())
+	}
+	resp, err = doRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return 0, fmt.Errorf("no total size in Content-Range %q", resp.Header.Get("Content-Range"))
+	case http.StatusOK:
+		if resp.ContentLength >= 0 {
+			return resp.ContentLength, nil
+		}
+		return io.Copy(io.Discard, resp.Body)
+	}
+	return 0, &statusError{Code: resp.StatusCode}
+}
+
+// pageWeight sums the sizes of the page's images and the assets found by
+// extractAssets. Each URL is requested once per run, and counted once per
+// page. Images are sized by the same ranged request -image-dims uses.
+func pageWeight(ctx context.Context, images []string, assets map[string][]string, base *url.URL) *PageWeight {
+	w := &PageWeight{ByType: make(map[string]ResourceWeight)}
+	seen := make(map[string]bool)
+	measure := func(kind, abs string) {
+		if seen[abs] {
+			return
+		}
+		seen[abs] = true
+		var size int64
+		if kind == "image" {
+			size = probeImage(ctx, abs).Size
+		} else {
+			size = assetSize(ctx, abs)
+		}
+		if size < 0 {
+			w.Unknown++
+			return
+		}
+		rw := w.ByType[kind]
+		rw.Count++
+		rw.Bytes += size
+		w.ByType[kind] = rw
+		w.Total += size
+	}
+	for _, src := range images {
+		if abs, ok := resolveURL(base, src); ok && strings.HasPrefix(abs, "http") {
+			measure("image", abs)
+		}
+	}
+	for _, kind := range []string{"script", "stylesheet"} {
+		for _, abs := range assets[kind] {
+			measure(kind, abs)
+		}
+	}
+	return w
+}
+
+// assetSize returns the cached size of the asset at abs, fetching it with
+// fetchAssetSize on first use, or -1 if it could not be determined.
+func assetSize(ctx context.Context, abs string) int64 {
+	weightCache.Lock()
+	size, cached := weightCache.m[abs]
+	weightCache.Unlock()
+	if cached {
+		return size
+	}
+	size, err := fetchAssetSize(ctx, abs)
+	if err != nil {
+		size = -1
+	}
+	weightCache.Lock()
+	weightCache.m[abs] = size
+	weightCache.Unlock()
+	return size
+}
+
+// add folds the weight of another page into w.
+func (w *PageWeight) add(o *PageWeight) {
+	if w.ByType == nil {
+		w.ByType = make(map[string]ResourceWeight)
+	}
+	w.Total += o.Total
+	w.Unknown += o.Unknown
+	for kind, rw := range o.ByType {
+		sum := w.ByType[kind]
+		sum.Count += rw.Count
+		sum.Bytes += rw.Bytes
+		w.ByType[kind] = sum
+	}
+}
+
+// String formats the weight for plain-text output.
+func (w *PageWeight) String() string {
+	kinds := make([]string, 0, len(w.ByType))
+	for kind := range w.ByType {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		rw := w.ByType[kind]
+		parts[i] = fmt.Sprintf("%s %s in %d", kind, formatBytes(rw.Bytes), rw.Count)
+	}
+	s := formatBytes(w.Total)
+	if len(parts) > 0 {
+		s += " (" + strings.Join(parts, ", ") + ")"
+	}
+	if w.Unknown > 0 {
+		s += fmt.Sprintf(", %d of unknown size", w.Unknown)
+	}
+	return s
+}
+
+// formatBytes formats n bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}